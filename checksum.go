@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// fileChecksum returns the hex SHA-256 of a local file after template rendering,
+// which is what Helm's `include ... | sha256sum` puts in a checksum annotation
+// for a rendered template
+func fileChecksum(filePath string, opts ParseOptions) (string, error) {
+	data, err := readLocalFile(filePath, opts)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// compareChecksumAnnotation compares the checksum annotation of a deployed resource
+// with the checksum of its local file. It returns nil when the annotation is not
+// set or matches.
+func compareChecksumAnnotation(annotation, localChecksum string, deployed *DeployedData) *FieldDifference {
+	deployedChecksum, ok := deployed.Annotations[annotation]
+	if !ok || deployedChecksum == localChecksum {
+		return nil
+	}
+	return &FieldDifference{Field: fmt.Sprintf("annotation %s", annotation), Local: localChecksum, Deployed: deployedChecksum}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterResource is a resource read from a source cluster, standing in for a
+// local manifest when two clusters are compared
+type clusterResource struct {
+	kind string
+	data *DeployedData
+}
+
+func (r *clusterResource) GetName() string                   { return r.data.Name }
+func (r *clusterResource) GetNamespace() string              { return r.data.Namespace }
+func (r *clusterResource) GetKind() string                   { return r.kind }
+func (r *clusterResource) GetLocalData() map[string]string   { return r.data.Data }
+func (r *clusterResource) GetFinalizers() []string           { return r.data.Finalizers }
+func (r *clusterResource) GetBinaryKeys() map[string]bool    { return r.data.BinaryKeys }
+func (r *clusterResource) GetType() string                   { return r.data.SecretType }
+func (r *clusterResource) GetLabels() map[string]string      { return r.data.Labels }
+func (r *clusterResource) GetAnnotations() map[string]string { return r.data.Annotations }
+func (r *clusterResource) GetImmutable() *bool               { return r.data.Immutable }
+
+// GetMergeField returns the field a snippet for this resource is written under
+func (r *clusterResource) GetMergeField() string {
+	if r.kind == "Secret" {
+		return "stringData"
+	}
+	return "data"
+}
+
+// listClusterResources returns every Secret and ConfigMap in namespace of the
+// source cluster, Secrets first
+func listClusterResources(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]LocalResource, error) {
+	var resources []LocalResource
+
+	secrets, err := listDeployedSecrets(ctx, clientset, namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		resources = append(resources, &clusterResource{kind: "Secret", data: secret})
+	}
+
+	configs, err := listDeployedConfigs(ctx, clientset, namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		resources = append(resources, &clusterResource{kind: "ConfigMap", data: config})
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI escape codes used to colorize the text report
+const (
+	colorReset  = "\033[0m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// colorEnabled controls whether colorize emits escape codes
+var colorEnabled bool
+
+// resolveColor decides whether to colorize output for the -color mode.
+// In auto mode color is used only when stdout is a terminal and NO_COLOR is unset.
+func resolveColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid color mode '%s' (expected auto, always or never)", mode)
+	}
+}
+
+// colorize wraps text in the given color when color is enabled
+func colorize(color, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	return color + text + colorReset
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// resourceKey identifies a resource by kind, namespace and name
+func resourceKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// localGetter loads the resources from a second set of local files and returns a
+// resourceGetter that matches them by kind, namespace and name. target may be a
+// single file or a directory scanned with the same patterns as -dir.
+func localGetter(target, patternStr string, recursive bool, parseOpts ParseOptions) (resourceGetter, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("error reading compare target: %w", err)
+	}
+
+	files := []string{target}
+	if info.IsDir() {
+		files, err = findFiles(target, patternStr, recursive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resources := make(map[string]LocalResource)
+	for _, file := range files {
+		fileResources, err := parseLocalFile(file, parseOpts)
+		if err != nil {
+			errorf("Error parsing YAML file '%s': %v\n", filepath.Base(file), err)
+			continue
+		}
+		for _, resource := range fileResources {
+			resources[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())] = resource
+		}
+	}
+
+	return func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		other, ok := resources[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())]
+		if !ok {
+			return nil, nil
+		}
+		return &DeployedData{
+			Type:        other.GetKind(),
+			Name:        other.GetName(),
+			Namespace:   other.GetNamespace(),
+			Data:        other.GetLocalData(),
+			BinaryKeys:  other.GetBinaryKeys(),
+			SecretType:  other.GetType(),
+			Immutable:   other.GetImmutable(),
+			Finalizers:  other.GetFinalizers(),
+			Labels:      other.GetLabels(),
+			Annotations: other.GetAnnotations(),
+		}, nil
+	}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read from the working directory when -config is not given
+const defaultConfigFile = ".secret-compare.yaml"
+
+// loadConfigFile sets flag defaults from a YAML config file whose keys are flag
+// names, e.g. "ignore-keys: ca.crt". Flags given on the command line win over
+// the file. A missing default file is not an error; a missing -config file is.
+func loadConfigFile(fs *flag.FlagSet, path string) (string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error reading config file: %w", err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("error decoding config file '%s': %w", path, err)
+	}
+
+	setOnCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setOnCommandLine[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			return "", fmt.Errorf("unknown option '%s' in config file '%s'", name, path)
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+		_, repeatable := fs.Lookup(name).Value.(*stringList)
+		for _, value := range configValues(values[name], repeatable) {
+			if err := fs.Set(name, value); err != nil {
+				return "", fmt.Errorf("invalid value for '%s' in config file '%s': %w", name, path, err)
+			}
+		}
+	}
+	return path, nil
+}
+
+// configValues converts a config file value into flag values. Lists become a
+// comma-separated value, or one value per item for repeatable flags such as -file.
+func configValues(value interface{}, repeatable bool) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return []string{fmt.Sprint(value)}
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = fmt.Sprint(item)
+	}
+	if repeatable {
+		return values
+	}
+	return []string{strings.Join(values, ",")}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testFlags is a flag set with a flag of each kind the tool uses
+type testFlags struct {
+	fs         *flag.FlagSet
+	ignoreKeys *string
+	mask       *bool
+	concurrent *int
+	files      *stringList
+}
+
+// newTestFlags returns testFlags with the tool's defaults
+func newTestFlags() testFlags {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f := testFlags{
+		fs:         fs,
+		ignoreKeys: fs.String("ignore-keys", "", ""),
+		mask:       fs.Bool("mask", true, ""),
+		concurrent: fs.Int("concurrency", 4, ""),
+		files:      &stringList{},
+	}
+	fs.Var(f.files, "file", "")
+	fs.String("config", "", "")
+	return f
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	config := `ignore-keys: [ca.crt, "token-*"]
+mask: false
+concurrency: 8
+file:
+  - a-secret.yaml
+  - b-secret.yaml
+`
+	tests := []struct {
+		name           string
+		args           []string
+		wantIgnoreKeys string
+		wantMask       bool
+		wantConcurrent int
+		wantFiles      []string
+	}{
+		{
+			name:           "file sets defaults",
+			wantIgnoreKeys: "ca.crt,token-*",
+			wantMask:       false,
+			wantConcurrent: 8,
+			wantFiles:      []string{"a-secret.yaml", "b-secret.yaml"},
+		},
+		{
+			name:           "command line wins over the file",
+			args:           []string{"-ignore-keys", "password", "-mask=true", "-file", "c-secret.yaml"},
+			wantIgnoreKeys: "password",
+			wantMask:       true,
+			wantConcurrent: 8,
+			wantFiles:      []string{"c-secret.yaml"},
+		},
+		{
+			name:           "command line value equal to the default still wins",
+			args:           []string{"-concurrency", "4"},
+			wantIgnoreKeys: "ca.crt,token-*",
+			wantMask:       false,
+			wantConcurrent: 4,
+			wantFiles:      []string{"a-secret.yaml", "b-secret.yaml"},
+		},
+	}
+	path := writeTempFile(t, "secret-compare.yaml", config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFlags()
+			if err := f.fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := loadConfigFile(f.fs, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded != path {
+				t.Errorf("loaded %q, want %q", loaded, path)
+			}
+			if *f.ignoreKeys != tt.wantIgnoreKeys {
+				t.Errorf("ignore-keys = %q, want %q", *f.ignoreKeys, tt.wantIgnoreKeys)
+			}
+			if *f.mask != tt.wantMask {
+				t.Errorf("mask = %v, want %v", *f.mask, tt.wantMask)
+			}
+			if *f.concurrent != tt.wantConcurrent {
+				t.Errorf("concurrency = %d, want %d", *f.concurrent, tt.wantConcurrent)
+			}
+			if got := []string(*f.files); !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("file = %q, want %q", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"unknown key", "ignore-key: ca.crt\n", "unknown option 'ignore-key'"},
+		{"config key", "config: other.yaml\n", "unknown option 'config'"},
+		{"invalid value", "concurrency: many\n", "invalid value for 'concurrency'"},
+		{"not a mapping", "- mask\n", "error decoding config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFlags()
+			_, err := loadConfigFile(f.fs, writeTempFile(t, "secret-compare.yaml", tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestLoadConfigFileMissing checks that only an explicitly given file must exist
+func TestLoadConfigFileMissing(t *testing.T) {
+	f := newTestFlags()
+	if _, err := loadConfigFile(f.fs, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing -config file was not reported")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	loaded, err := loadConfigFile(newTestFlags().fs, "")
+	if err != nil || loaded != "" {
+		t.Errorf("missing default file: loaded %q, error %v; want neither", loaded, err)
+	}
+}
+
+// TestConfigFileWatchInChild checks that a watch child, started with -watch=false,
+// does not turn watching back on from "watch: true" in the config file
+func TestConfigFileWatchInChild(t *testing.T) {
+	f := newTestFlags()
+	watch := f.fs.Bool("watch", false, "")
+	args := append([]string{"-watch=false"}, withoutWatchFlag([]string{"-watch", "-mask=false"})...)
+	if err := f.fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfigFile(f.fs, writeTempFile(t, "secret-compare.yaml", "watch: true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if *watch {
+		t.Error("watch child re-enabled -watch from the config file")
+	}
+	if *f.mask {
+		t.Error("other flags were not passed to the watch child")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffLine is a single line of a line-based diff
+type diffLine struct {
+	Op   byte // ' ' for unchanged, '-' for removed, '+' for added
+	Text string
+}
+
+// maxDiffCells caps the size of the LCS table (lines of a times lines of b),
+// about 32 MB, so large multi-line values cannot exhaust memory
+const maxDiffCells = 4 << 20
+
+// diffLines computes a minimal line diff from a to b using the longest common
+// subsequence. Common leading and trailing lines are matched first; when the
+// lines left in between are too many for the LCS table, they are shown as
+// removed and then added instead of minimally.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, line := range a[:prefix] {
+		lines = append(lines, diffLine{' ', line})
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(middleA)*len(middleB) > maxDiffCells {
+		for _, line := range middleA {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range middleB {
+			lines = append(lines, diffLine{'+', line})
+		}
+	} else {
+		lines = append(lines, lcsDiff(middleA, middleB)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', line})
+	}
+	return lines
+}
+
+// lcsDiff computes a minimal line diff from a to b with a full LCS table
+func lcsDiff(a, b []string) []diffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// unifiedDiff renders the difference between two values in the style of `diff -u`,
+// keeping up to context unchanged lines around each change.
+func unifiedDiff(fromName, toName, from, to string, context int) string {
+	lines := diffLines(strings.Split(from, "\n"), strings.Split(to, "\n"))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Walk the diff, grouping changes whose context windows overlap into hunks
+	for start := 0; start < len(lines); {
+		if lines[start].Op == ' ' {
+			start++
+			continue
+		}
+		hunkStart := start - context
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := start
+		for k := start; k < len(lines); k++ {
+			if lines[k].Op != ' ' {
+				hunkEnd = k
+			} else if k-hunkEnd > 2*context {
+				break
+			}
+		}
+		hunkEnd += context
+		if hunkEnd >= len(lines) {
+			hunkEnd = len(lines) - 1
+		}
+
+		// Line numbers of the hunk in both versions (1-based)
+		fromLine, toLine := 1, 1
+		for _, l := range lines[:hunkStart] {
+			if l.Op != '+' {
+				fromLine++
+			}
+			if l.Op != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, l := range lines[hunkStart : hunkEnd+1] {
+			if l.Op != '+' {
+				fromCount++
+			}
+			if l.Op != '-' {
+				toCount++
+			}
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, l := range lines[hunkStart : hunkEnd+1] {
+			fmt.Fprintf(&sb, "%c%s\n", l.Op, l.Text)
+		}
+		start = hunkEnd + 1
+	}
+	return sb.String()
+}
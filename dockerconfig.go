@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// dockerConfigKey is the key holding the registry credentials of a
+// kubernetes.io/dockerconfigjson Secret
+const dockerConfigKey = ".dockerconfigjson"
+
+// dockerConfig is the content of a .dockerconfigjson value
+type dockerConfig struct {
+	Auths map[string]map[string]interface{} `json:"auths"`
+}
+
+// compareDockerConfigs re-examines a differing .dockerconfigjson value by parsing
+// both sides and comparing them per registry. A difference that is only JSON
+// formatting is dropped; otherwise the difference is annotated with the registries
+// added, removed or changed. Credentials are never included in the notes. Values
+// that do not parse keep the raw comparison.
+func compareDockerConfigs(differences []SecretDifference) []SecretDifference {
+	for i, diff := range differences {
+		if diff.Key != dockerConfigKey || diff.Local == nil || diff.Deployed == nil {
+			continue
+		}
+		local, err := parseDockerConfig(*diff.Local)
+		if err != nil {
+			debugf("Comparing local key '%s' as raw text: %v", diff.Key, err)
+			return differences
+		}
+		deployed, err := parseDockerConfig(*diff.Deployed)
+		if err != nil {
+			debugf("Comparing deployed key '%s' as raw text: %v", diff.Key, err)
+			return differences
+		}
+		notes := describeDockerConfigChanges(local, deployed)
+		if len(notes) == 0 {
+			infof("Registry credentials in key '%s' are identical; only the JSON formatting differs", diff.Key)
+			return append(differences[:i:i], differences[i+1:]...)
+		}
+		differences[i].Notes = notes
+		return differences
+	}
+	return differences
+}
+
+// parseDockerConfig decodes a .dockerconfigjson value
+func parseDockerConfig(value string) (*dockerConfig, error) {
+	var config dockerConfig
+	if err := json.Unmarshal([]byte(value), &config); err != nil {
+		return nil, fmt.Errorf("error parsing docker config: %w", err)
+	}
+	if config.Auths == nil {
+		return nil, fmt.Errorf("no auths found in docker config")
+	}
+	return &config, nil
+}
+
+// describeDockerConfigChanges lists the registries that differ between two docker
+// configs. It returns nil when both configs hold the same credentials.
+func describeDockerConfigChanges(local, deployed *dockerConfig) []string {
+	registries := make(map[string]struct{})
+	for registry := range local.Auths {
+		registries[registry] = struct{}{}
+	}
+	for registry := range deployed.Auths {
+		registries[registry] = struct{}{}
+	}
+	sorted := make([]string, 0, len(registries))
+	for registry := range registries {
+		sorted = append(sorted, registry)
+	}
+	sort.Strings(sorted)
+
+	var notes []string
+	for _, registry := range sorted {
+		localAuth, inLocal := local.Auths[registry]
+		deployedAuth, inDeployed := deployed.Auths[registry]
+		switch {
+		case !inDeployed:
+			notes = append(notes, fmt.Sprintf("registry %s only in local", registry))
+		case !inLocal:
+			notes = append(notes, fmt.Sprintf("registry %s only in deployed", registry))
+		default:
+			if changed := changedRegistryFields(localAuth, deployedAuth); len(changed) > 0 {
+				notes = append(notes, fmt.Sprintf("registry %s: %s changed", registry, strings.Join(changed, ", ")))
+			}
+		}
+	}
+	return notes
+}
+
+// changedRegistryFields returns the names of the fields that differ between two
+// registry entries. The "auth" field is expanded into username and password, so
+// an entry using "auth" matches one with the same separate fields.
+func changedRegistryFields(local, deployed map[string]interface{}) []string {
+	local, deployed = expandRegistryAuth(local), expandRegistryAuth(deployed)
+	fields := make(map[string]struct{})
+	for field := range local {
+		fields[field] = struct{}{}
+	}
+	for field := range deployed {
+		fields[field] = struct{}{}
+	}
+	var changed []string
+	for field := range fields {
+		if !reflect.DeepEqual(local[field], deployed[field]) {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// expandRegistryAuth returns a copy of entry with a decodable "auth" field replaced
+// by the username and password it encodes
+func expandRegistryAuth(entry map[string]interface{}) map[string]interface{} {
+	expanded := make(map[string]interface{}, len(entry))
+	for field, value := range entry {
+		expanded[field] = value
+	}
+	auth, ok := entry["auth"].(string)
+	if !ok {
+		return expanded
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		return expanded
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return expanded
+	}
+	delete(expanded, "auth")
+	expanded["username"] = username
+	expanded["password"] = password
+	return expanded
+}
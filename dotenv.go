@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseDotenvResource reads a dotenv file and wraps its variables in a synthetic
+// Secret named and namespaced from opts, so it can be compared like a manifest
+func parseDotenvResource(filePath string, opts ParseOptions) ([]LocalResource, error) {
+	if opts.DotenvName == "" {
+		return nil, fmt.Errorf("a secret name is required for dotenv files (set -dotenv-name)")
+	}
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("a namespace is required for dotenv files (set -namespace)")
+	}
+
+	data, err := readLocalFile(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing dotenv: %w", err)
+	}
+
+	secret := &KubernetesSecret{Kind: "Secret", StringData: values}
+	secret.Metadata.Name = opts.DotenvName
+	secret.Metadata.Namespace = opts.Namespace
+	return []LocalResource{secret}, nil
+}
+
+// parseDotenv parses KEY=value lines. Blank lines and lines starting with # are
+// skipped, an optional "export " prefix is allowed, unquoted values end at a " #"
+// comment, single-quoted values are literal and double-quoted values support
+// \n, \t, \" and \\ escapes. Quoted values may span multiple lines.
+func parseDotenv(content string) (map[string]string, error) {
+	values := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if idx := strings.Index(rest, " #"); idx >= 0 {
+				rest = rest[:idx]
+			}
+			values[key] = strings.TrimSpace(rest)
+			continue
+		}
+
+		// Quoted value: keep reading lines until the closing quote
+		quote := rest[0]
+		raw := rest[1:]
+		for {
+			if end := closingQuote(raw, quote); end >= 0 {
+				raw = raw[:end]
+				break
+			}
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value for '%s'", lineNo, key)
+			}
+			raw += "\n" + lines[i]
+		}
+		if quote == '"' {
+			raw = unescapeDotenv(raw)
+		}
+		values[key] = raw
+	}
+	return values, nil
+}
+
+// closingQuote returns the index of the first unescaped quote in s, or -1.
+// Backslash escapes only apply inside double quotes.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenv expands the escapes supported in double-quoted dotenv values
+func unescapeDotenv(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case '"', '\\', '$':
+			sb.WriteByte(s[i])
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dumpValues writes the local and deployed value of every differing key of a
+// result to <dir>/<namespace>/<kind>-<name>/{local,deployed}/<key>, so external
+// tools such as openssl can be run on them. Files are overwritten but never
+// removed. It returns the directory of the resource, or "" if nothing was written.
+func dumpValues(dir string, result ComparisonResult) (string, error) {
+	if len(result.Differences) == 0 {
+		return "", nil
+	}
+	resourceDir := filepath.Join(dir, safePathElement(result.Namespace), strings.ToLower(result.Kind)+"-"+safePathElement(result.Name))
+	for _, diff := range result.Differences {
+		for side, value := range map[string]*string{"local": diff.Local, "deployed": diff.Deployed} {
+			if value == nil {
+				continue
+			}
+			sideDir := filepath.Join(resourceDir, side)
+			if err := os.MkdirAll(sideDir, 0o700); err != nil {
+				return "", fmt.Errorf("error creating dump directory: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(sideDir, safePathElement(diff.Key)), []byte(*value), 0o600); err != nil {
+				return "", fmt.Errorf("error writing dumped value: %w", err)
+			}
+		}
+	}
+	return resourceDir, nil
+}
+
+// safePathElement keeps a name from escaping its directory. Kubernetes names
+// and keys are already safe; this only guards against hand-written manifests.
+func safePathElement(name string) string {
+	name = strings.ReplaceAll(name, string(filepath.Separator), "_")
+	if name == "." || name == ".." || name == "" {
+		return "_" + name
+	}
+	return name
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventWriter emits one NDJSON event per lifecycle action.
+// All writes go through a single mutex so lines stay valid under concurrency.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventWriter returns an EventWriter that writes to w
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+// Emit writes a single event with a timestamp and the given fields
+func (e *EventWriter) Emit(event string, fields map[string]interface{}) {
+	record := map[string]interface{}{
+		"event":     event,
+		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	for k, v := range fields {
+		record[k] = v
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(record)
+}
+
+// emitResultEvents emits a diff_found event for every difference in a result.
+// Values are never included.
+func emitResultEvents(e *EventWriter, result ComparisonResult) {
+	emit := func(fields map[string]interface{}) {
+		fields["kind"] = result.Kind
+		fields["name"] = result.Name
+		fields["namespace"] = result.Namespace
+		e.Emit("diff_found", fields)
+	}
+	if result.NotDeployed {
+		emit(map[string]interface{}{"status": "NOT_DEPLOYED"})
+	}
+	for _, diff := range result.Differences {
+		emit(map[string]interface{}{"key": diff.Key, "status": diffStatus(diff)})
+	}
+	for _, f := range result.FinalizersOnlyInLocal {
+		emit(map[string]interface{}{"finalizer": f, "status": "ONLY_IN_LOCAL"})
+	}
+	for _, f := range result.FinalizersOnlyInDeployed {
+		emit(map[string]interface{}{"finalizer": f, "status": "ONLY_IN_DEPLOYED"})
+	}
+	for _, field := range result.FieldDifferences {
+		emit(map[string]interface{}{"field": field.Field, "status": "DIFFERENT"})
+	}
+	for _, diff := range result.LabelDifferences {
+		emit(map[string]interface{}{"label": diff.Key, "status": diffStatus(diff)})
+	}
+	for _, diff := range result.AnnotationDifferences {
+		emit(map[string]interface{}{"annotation": diff.Key, "status": diffStatus(diff)})
+	}
+}
+
+// diffStatus returns the status name of a difference
+func diffStatus(diff SecretDifference) string {
+	switch {
+	case diff.Local != nil && diff.Deployed != nil:
+		return "DIFFERENT"
+	case diff.Local != nil:
+		return "ONLY_IN_LOCAL"
+	default:
+		return "ONLY_IN_DEPLOYED"
+	}
+}
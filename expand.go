@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"sort"
+)
+
+// expandEnvValues returns a copy of data with ${VAR} and $VAR references in its
+// values replaced from the environment, as a deploy pipeline would render them.
+// Variables that are not set expand to an empty string and are warned about.
+// Keys from binaryData are left untouched.
+func expandEnvValues(data map[string]string, resource LocalResource) map[string]string {
+	binary := resource.GetBinaryKeys()
+	expanded := make(map[string]string, len(data))
+	for key, value := range data {
+		if binary[key] {
+			expanded[key] = value
+			continue
+		}
+		var unset []string
+		expanded[key] = os.Expand(value, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				unset = append(unset, name)
+			}
+			return value
+		})
+		sort.Strings(unset)
+		for _, name := range unset {
+			warnf("Variable '%s' referenced by key '%s' of %s '%s' in namespace '%s' is not set; it expands to an empty string", name, key, resource.GetKind(), resource.GetName(), resource.GetNamespace())
+		}
+	}
+	return expanded
+}
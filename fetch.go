@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/kubernetes"
+)
+
+// fetchResult holds the outcome of looking up one deployed resource
+type fetchResult struct {
+	Deployed *DeployedData
+	Err      error
+	Duration time.Duration
+}
+
+// resourceGetter looks up the counterpart of a local resource to compare against.
+// It returns nil when no counterpart exists.
+type resourceGetter func(ctx context.Context, resource LocalResource) (*DeployedData, error)
+
+// errNamespaceNotFound marks a lookup that found nothing because the namespace itself does not exist
+var errNamespaceNotFound = errors.New("namespace not found")
+
+// isNamespaceNotFound reports whether a lookup failed because its namespace does not exist
+func isNamespaceNotFound(err error) bool {
+	return errors.Is(err, errNamespaceNotFound)
+}
+
+// retryBaseDelay is the delay before the first retry; it doubles on every attempt
+const retryBaseDelay = 250 * time.Millisecond
+
+// clusterGetter returns a resourceGetter that fetches resources from the cluster,
+// making up to attempts tries when the API server fails transiently
+func clusterGetter(clientset kubernetes.Interface, timeout time.Duration, attempts int) resourceGetter {
+	namespaces := &namespaceChecker{clientset: clientset, exists: make(map[string]bool)}
+	return func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		delay := retryBaseDelay
+		for attempt := 1; ; attempt++ {
+			deployed, err := getDeployed(ctx, clientset, resource, timeout)
+			if err == nil && deployed == nil && !namespaces.check(ctx, resource.GetNamespace(), timeout) {
+				return nil, fmt.Errorf("namespace '%s' does not exist in the cluster: %w", resource.GetNamespace(), errNamespaceNotFound)
+			}
+			if err == nil || attempt >= attempts || !isTransientError(err) {
+				return deployed, err
+			}
+			logCtxFrom(ctx).debugf("Retrying %s '%s' in namespace '%s' in %s (attempt %d/%d): %v", resource.GetKind(), resource.GetName(), resource.GetNamespace(), delay, attempt+1, attempts, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, err
+			}
+			delay *= 2
+		}
+	}
+}
+
+// namespaceChecker looks up whether namespaces exist, once per namespace
+type namespaceChecker struct {
+	clientset kubernetes.Interface
+	mu        sync.Mutex
+	exists    map[string]bool
+}
+
+// check reports whether a namespace exists. It is only used to explain a missing
+// resource, so when the namespace cannot be read (e.g. no RBAC permission to get
+// namespaces) it is assumed to exist.
+func (n *namespaceChecker) check(ctx context.Context, namespace string, timeout time.Duration) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if exists, ok := n.exists[namespace]; ok {
+		return exists
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, err := n.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	exists := !apierrors.IsNotFound(err)
+	if err != nil && exists {
+		logCtxFrom(ctx).debugf("Could not check whether namespace '%s' exists: %v", namespace, err)
+	}
+	n.exists[namespace] = exists
+	return exists
+}
+
+// isTransientError reports whether a failed lookup is worth retrying.
+// NotFound is definitive and never retried.
+func isTransientError(err error) bool {
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err)
+}
+
+// cachedGetter wraps get so each resource is looked up at most once per run,
+// keyed by kind, namespace and name. Concurrent lookups of the same resource
+// wait for the first one. Results, including not-found and errors, are kept
+// in memory only.
+func cachedGetter(get resourceGetter) resourceGetter {
+	type entry struct {
+		done     chan struct{}
+		deployed *DeployedData
+		err      error
+	}
+	var mu sync.Mutex
+	cache := make(map[string]*entry)
+
+	return func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		key := resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())
+		mu.Lock()
+		e, ok := cache[key]
+		if !ok {
+			e = &entry{done: make(chan struct{})}
+			cache[key] = e
+		}
+		mu.Unlock()
+
+		if ok {
+			<-e.done
+			logCtxFrom(ctx).debugf("Reusing cached lookup of %s '%s' in namespace '%s'", resource.GetKind(), resource.GetName(), resource.GetNamespace())
+			return e.deployed, e.err
+		}
+		e.deployed, e.err = get(ctx, resource)
+		close(e.done)
+		return e.deployed, e.err
+	}
+}
+
+// getDeployed retrieves the deployed counterpart of a local resource,
+// giving up once timeout has elapsed
+func getDeployed(ctx context.Context, clientset kubernetes.Interface, resource LocalResource, timeout time.Duration) (*DeployedData, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var deployed *DeployedData
+	var err error
+	start := time.Now()
+	switch resource.GetKind() {
+	case "Secret":
+		deployed, err = getDeployedSecret(ctx, clientset, resource.GetNamespace(), resource.GetName())
+	case "ConfigMap":
+		deployed, err = getDeployedConfig(ctx, clientset, resource.GetNamespace(), resource.GetName())
+	default:
+		return nil, fmt.Errorf("unsupported resource type: %s", resource.GetKind())
+	}
+	if deployed != nil {
+		logCtxFrom(ctx).debugf("Lookup of %s '%s' in namespace '%s' took %s (resourceVersion %s)", resource.GetKind(), resource.GetName(), resource.GetNamespace(), time.Since(start).Round(time.Millisecond), deployed.ResourceVersion)
+	} else {
+		logCtxFrom(ctx).debugf("Lookup of %s '%s' in namespace '%s' took %s", resource.GetKind(), resource.GetName(), resource.GetNamespace(), time.Since(start).Round(time.Millisecond))
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("lookup of %s '%s' in namespace '%s' timed out after %s: %w", resource.GetKind(), resource.GetName(), resource.GetNamespace(), timeout, ctx.Err())
+	}
+	return deployed, err
+}
+
+// fetchPool looks up deployed resources in the background with a bounded pool
+// of workers. Results are read in the order of the resources with result, each
+// as soon as it is available, regardless of completion order.
+type fetchPool struct {
+	results []fetchResult
+	done    []chan struct{} // closed once the result at the same index is set
+	wg      sync.WaitGroup
+	lookups int64 // lookups actually made, read with wait
+}
+
+// startFetch starts looking up the deployed counterpart of every resource.
+// Each lookup logs with the logCtx returned by logCtxOf for its resource, or
+// with the resource alone when logCtxOf is nil. onFetched, if set, is called
+// from the worker goroutines as each lookup completes and must be safe for
+// concurrent use. Once ctx is cancelled, the lookups not started yet are
+// skipped and their results hold ctx.Err().
+func startFetch(ctx context.Context, get resourceGetter, resources []LocalResource, concurrency int, logCtxOf func(LocalResource) logCtx, onFetched func(LocalResource, fetchResult)) *fetchPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if logCtxOf == nil {
+		logCtxOf = func(resource LocalResource) logCtx { return resourceLogCtx("", resource) }
+	}
+
+	p := &fetchPool{results: make([]fetchResult, len(resources)), done: make([]chan struct{}, len(resources))}
+	for i := range p.done {
+		p.done[i] = make(chan struct{})
+	}
+	jobs := make(chan int)
+
+	for w := 0; w < concurrency; w++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					p.results[i] = fetchResult{Err: err}
+					close(p.done[i])
+					continue
+				}
+				start := time.Now()
+				deployed, err := get(withLogCtx(ctx, logCtxOf(resources[i])), resources[i])
+				atomic.AddInt64(&p.lookups, 1)
+				// Each worker writes only to its own index, so no locking is needed
+				p.results[i] = fetchResult{Deployed: deployed, Err: err, Duration: time.Since(start)}
+				if onFetched != nil {
+					onFetched(resources[i], p.results[i])
+				}
+				close(p.done[i])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range resources {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	return p
+}
+
+// result waits for the lookup of the resource at index i and returns it
+func (p *fetchPool) result(i int) fetchResult {
+	<-p.done[i]
+	return p.results[i]
+}
+
+// wait waits for every lookup to finish or be skipped, and returns the number
+// of lookups made
+func (p *fetchPool) wait() int {
+	p.wg.Wait()
+	return int(atomic.LoadInt64(&p.lookups))
+}
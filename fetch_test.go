@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testSecret returns a local Secret manifest in namespace
+func testSecret(namespace, name string) *KubernetesSecret {
+	secret := &KubernetesSecret{Kind: "Secret", StringData: map[string]string{"key": name}}
+	secret.Metadata.Name, secret.Metadata.Namespace = name, namespace
+	return secret
+}
+
+// TestStartFetchKeepsOrder fetches with several workers whose lookups finish
+// in random order, and checks every result lands at the index of its resource.
+// Run with -race to check the pool for data races.
+func TestStartFetchKeepsOrder(t *testing.T) {
+	objects := []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}
+	var resources []LocalResource
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("secret-%02d", i)
+		resources = append(resources, testSecret("default", name))
+		// Every third resource is not deployed
+		if i%3 != 0 {
+			objects = append(objects, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Data:       map[string][]byte{"key": []byte(name)},
+			})
+		}
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	cluster := clusterGetter(clientset, time.Second, 1)
+	jitter := func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		return cluster(ctx, resource)
+	}
+
+	var fetched int64
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	onFetched := func(resource LocalResource, result fetchResult) {
+		atomic.AddInt64(&fetched, 1)
+		mu.Lock()
+		seen[resource.GetName()] = true
+		mu.Unlock()
+	}
+
+	for _, concurrency := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			atomic.StoreInt64(&fetched, 0)
+			pool := startFetch(context.Background(), jitter, resources, concurrency, nil, onFetched)
+			for i := range resources {
+				result, name := pool.result(i), resources[i].GetName()
+				if result.Err != nil {
+					t.Errorf("%s: unexpected error: %v", name, result.Err)
+					continue
+				}
+				switch {
+				case i%3 == 0 && result.Deployed != nil:
+					t.Errorf("%s: got deployed %s, want not found", name, result.Deployed.Name)
+				case i%3 != 0 && (result.Deployed == nil || result.Deployed.Name != name || result.Deployed.Data["key"] != name):
+					t.Errorf("result %d is not %s: %+v", i, name, result.Deployed)
+				}
+			}
+			if lookups := pool.wait(); lookups != len(resources) {
+				t.Errorf("made %d lookups, want %d", lookups, len(resources))
+			}
+			if got := atomic.LoadInt64(&fetched); got != int64(len(resources)) {
+				t.Errorf("onFetched called %d times, want %d", got, len(resources))
+			}
+		})
+	}
+	if len(seen) != len(resources) {
+		t.Errorf("onFetched saw %d resources, want %d", len(seen), len(resources))
+	}
+}
+
+// TestCachedGetterLooksUpOnce checks that concurrent lookups of the same
+// resource share a single call
+func TestCachedGetterLooksUpOnce(t *testing.T) {
+	var calls int64
+	get := cachedGetter(func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(5 * time.Millisecond)
+		return &DeployedData{Name: resource.GetName()}, nil
+	})
+	resources := make([]LocalResource, 20)
+	for i := range resources {
+		resources[i] = testSecret("default", "shared")
+	}
+	pool := startFetch(context.Background(), get, resources, 8, nil, nil)
+	for i := range resources {
+		if result := pool.result(i); result.Deployed == nil || result.Deployed.Name != "shared" {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	pool.wait()
+	if calls != 1 {
+		t.Errorf("underlying getter called %d times, want 1", calls)
+	}
+}
+
+// failingGets makes the first failures Secret gets of clientset fail with err,
+// returning the number of gets made so far
+func failingGets(clientset *fake.Clientset, failures int, err error) func() int64 {
+	var gets int64
+	clientset.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.AddInt64(&gets, 1) <= int64(failures) {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return func() int64 { return atomic.LoadInt64(&gets) }
+}
+
+func TestClusterGetterRetries(t *testing.T) {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		attempts  int
+		wantGets  int64
+		wantFound bool
+		wantErr   bool
+	}{
+		{"server timeout then success", 2, apierrors.NewServerTimeout(secrets, "get", 1), 3, 3, true, false},
+		{"too many requests then success", 1, apierrors.NewTooManyRequests("slow down", 1), 3, 2, true, false},
+		{"server timeout on every attempt", 5, apierrors.NewServerTimeout(secrets, "get", 1), 2, 2, false, true},
+		{"forbidden is not retried", 5, apierrors.NewForbidden(secrets, "db", nil), 3, 1, false, true},
+		{"not found is not retried", 5, apierrors.NewNotFound(secrets, "db"), 3, 1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}, Data: map[string][]byte{"key": []byte("db")}},
+			)
+			gets := failingGets(clientset, tt.failures, tt.err)
+			deployed, err := clusterGetter(clientset, time.Second, tt.attempts)(context.Background(), testSecret("default", "db"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if (deployed != nil) != tt.wantFound {
+				t.Errorf("deployed = %+v, want found %v", deployed, tt.wantFound)
+			}
+			if got := gets(); got != tt.wantGets {
+				t.Errorf("made %d gets, want %d", got, tt.wantGets)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server timeout", apierrors.NewServerTimeout(secrets, "get", 1), true},
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 1), true},
+		{"timeout", apierrors.NewTimeoutError("timed out", 1), true},
+		{"internal error", apierrors.NewInternalError(fmt.Errorf("boom")), true},
+		{"service unavailable", apierrors.NewServiceUnavailable("down"), true},
+		{"deadline exceeded", fmt.Errorf("error fetching secret: %w", context.DeadlineExceeded), true},
+		{"not found", apierrors.NewNotFound(secrets, "db"), false},
+		{"forbidden", apierrors.NewForbidden(secrets, "db", nil), false},
+		{"unauthorized", apierrors.NewUnauthorized("no"), false},
+		{"bad request", apierrors.NewBadRequest("bad"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterGetterMissingNamespace(t *testing.T) {
+	tests := []struct {
+		name             string
+		namespace        string
+		forbidNamespaces bool
+		wantMissingNs    bool
+	}{
+		{"missing secret in an existing namespace", "default", false, false},
+		{"missing namespace", "staging", false, true},
+		{"namespace that cannot be read is assumed to exist", "staging", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			var namespaceGets int64
+			clientset.PrependReactor("get", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				atomic.AddInt64(&namespaceGets, 1)
+				if tt.forbidNamespaces {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, tt.namespace, nil)
+				}
+				return false, nil, nil
+			})
+			get := clusterGetter(clientset, time.Second, 1)
+			for _, name := range []string{"db", "api"} {
+				deployed, err := get(context.Background(), testSecret(tt.namespace, name))
+				if deployed != nil {
+					t.Errorf("%s: got deployed %+v, want none", name, deployed)
+				}
+				if isNamespaceNotFound(err) != tt.wantMissingNs {
+					t.Errorf("%s: error = %v, want namespace not found %v", name, err, tt.wantMissingNs)
+				}
+				if tt.wantMissingNs && err.Error() != "namespace 'staging' does not exist in the cluster: namespace not found" {
+					t.Errorf("%s: unexpected message %q", name, err)
+				}
+				if !tt.wantMissingNs && err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+				}
+			}
+			if namespaceGets != 1 {
+				t.Errorf("namespace looked up %d times, want once", namespaceGets)
+			}
+		})
+	}
+}
+
+// TestStartFetchCancel reads the first result, cancels as -fail-fast does, and
+// checks the lookups not started yet are skipped
+func TestStartFetchCancel(t *testing.T) {
+	resources := make([]LocalResource, 50)
+	for i := range resources {
+		resources[i] = testSecret("default", fmt.Sprintf("secret-%02d", i))
+	}
+	var calls int64
+	get := func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		atomic.AddInt64(&calls, 1)
+		if resource.GetName() == "secret-00" {
+			return &DeployedData{Name: resource.GetName()}, nil
+		}
+		// Later lookups only finish once cancelled, like slow API calls
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	const concurrency = 4
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := startFetch(ctx, get, resources, concurrency, nil, nil)
+	if first := pool.result(0); first.Err != nil || first.Deployed == nil {
+		t.Fatalf("first result = %+v", first)
+	}
+	cancel()
+	lookups := pool.wait()
+
+	// The first lookup plus at most one in flight per worker
+	if lookups > concurrency+1 || calls != int64(lookups) {
+		t.Errorf("made %d lookups (getter called %d times) after cancelling, want at most %d", lookups, calls, concurrency+1)
+	}
+	for i := 1; i < len(resources); i++ {
+		if result := pool.result(i); !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result %d = %+v, want cancelled", i, result)
+		}
+	}
+}
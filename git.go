@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// changedFiles returns the absolute paths of the files in the git work tree
+// containing dir that changed since ref. Deleted files are left out.
+func changedFiles(dir, ref string) (map[string]bool, error) {
+	root, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	out, err := runGit(dir, "diff", "--name-only", "--diff-filter=d", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(out, "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			changed[filepath.Join(root, filepath.FromSlash(name))] = true
+		}
+	}
+	return changed, nil
+}
+
+// filterChanged returns the files that are in changed, comparing absolute paths
+func filterChanged(files []string, changed map[string]bool) ([]string, error) {
+	var filtered []string
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving path '%s': %w", file, err)
+		}
+		// The work tree root from git has symlinks resolved, so resolve ours too
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		if changed[abs] {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered, nil
+}
+
+// runGit runs a git command in dir and returns its output
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("error running git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("error running git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
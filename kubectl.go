@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// renderKubectlPatch renders a `kubectl patch` command that pushes the local value
+// of every key that is only in the local file or differs to the cluster.
+// Secret values are base64-encoded into data; binary ConfigMap values are left out
+// because their local encoding is unknown. It returns "" when nothing needs to be pushed.
+func renderKubectlPatch(result ComparisonResult) string {
+	data := make(map[string]string)
+	for _, diff := range result.Differences {
+		if diff.Local == nil {
+			continue
+		}
+		switch {
+		case result.Kind == "Secret":
+			data[diff.Key] = base64.StdEncoding.EncodeToString([]byte(*diff.Local))
+		case !diff.Binary:
+			data[diff.Key] = *diff.Local
+		}
+	}
+	if len(data) == 0 {
+		return ""
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("kubectl patch %s/%s -n %s --type=merge -p %s",
+		strings.ToLower(result.Kind), result.Name, result.Namespace, shellQuote(string(patch)))
+}
+
+// shellQuote quotes a value for POSIX shells using single quotes
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// logLevel is the severity of an operational log message
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// levelNames maps each level to its name in -log-level and in log lines
+var levelNames = map[logLevel]string{
+	levelDebug: "DEBUG",
+	levelInfo:  "INFO",
+	levelWarn:  "WARN",
+	levelError: "ERROR",
+}
+
+// minLogLevel is the lowest level that is logged
+var minLogLevel = levelInfo
+
+// jsonLogs writes every operational log line as a JSON object (-log-format json)
+var jsonLogs bool
+
+// logCtx names the file and resource a log line is about, added to JSON log lines
+type logCtx struct {
+	file     string
+	resource string
+}
+
+// resourceLogCtx returns the logCtx of a resource defined in file
+func resourceLogCtx(file string, resource LocalResource) logCtx {
+	return logCtx{file: file, resource: fmt.Sprintf("%s %s/%s", resource.GetKind(), resource.GetNamespace(), resource.GetName())}
+}
+
+// mainLogCtx is the logCtx of the main goroutine, which parses files and compares
+// resources one at a time. Fetch workers log with the logCtx of their own lookup
+// instead, carried in their context.
+var (
+	mainLogCtxMu sync.Mutex
+	mainLogCtx   logCtx
+)
+
+// setLogContext sets the logCtx of the following lines logged by the main goroutine
+func setLogContext(c logCtx) {
+	mainLogCtxMu.Lock()
+	defer mainLogCtxMu.Unlock()
+	mainLogCtx = c
+}
+
+// currentLogCtx returns the logCtx set with setLogContext
+func currentLogCtx() logCtx {
+	mainLogCtxMu.Lock()
+	defer mainLogCtxMu.Unlock()
+	return mainLogCtx
+}
+
+// logCtxKey is the context key of the logCtx added by withLogCtx
+type logCtxKey struct{}
+
+// withLogCtx returns a copy of ctx whose lookups log with c
+func withLogCtx(ctx context.Context, c logCtx) context.Context {
+	return context.WithValue(ctx, logCtxKey{}, c)
+}
+
+// logCtxFrom returns the logCtx added to ctx with withLogCtx, or that of the
+// main goroutine when there is none
+func logCtxFrom(ctx context.Context) logCtx {
+	if c, ok := ctx.Value(logCtxKey{}).(logCtx); ok {
+		return c
+	}
+	return currentLogCtx()
+}
+
+// jsonLogLine is one operational log line written with -log-format json
+type jsonLogLine struct {
+	Time     string          `json:"time"`
+	Level    string          `json:"level"`
+	Message  string          `json:"message"`
+	File     string          `json:"file,omitempty"`
+	Resource string          `json:"resource,omitempty"`
+	Summary  *JSONRunSummary `json:"summary,omitempty"`
+}
+
+// errorsLogged counts the errors reported through errorf; any of them makes
+// the run exit with exitError. It is atomic since fetch workers log too.
+var errorsLogged atomic.Int64
+
+// warningsLogged counts the warnings reported through warnf, such as retries
+// logged by fetch workers
+var warningsLogged atomic.Int64
+
+// Exit codes of a run. An operational error takes precedence over differences,
+// since the report may be incomplete.
+const (
+	exitMatch       = 0
+	exitDifferences = 1
+	exitError       = 2
+	exitInterrupted = 130 // like a shell reports a command stopped by Ctrl-C
+)
+
+// parseLogFormat parses a -log-format value, reporting whether logs are JSON
+func parseLogFormat(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported log format '%s' (expected text or json)", value)
+	}
+}
+
+// parseLogLevel parses a -log-level value such as "debug" or "warn"
+func parseLogLevel(value string) (logLevel, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(value, name) || (level == levelWarn && strings.EqualFold(value, "warning")) {
+			return level, nil
+		}
+	}
+	return levelInfo, fmt.Errorf("unsupported log level '%s' (expected debug, info, warn or error)", value)
+}
+
+// logf logs a message at level if it is enabled. Operational logs go to stderr
+// through the standard logger so stdout only carries the report.
+func (c logCtx) logf(level logLevel, format string, args ...interface{}) {
+	if level < minLogLevel {
+		return
+	}
+	c.writeLog(level, fmt.Sprintf(format, args...))
+}
+
+// writeLog writes a log line as "LEVEL message", or as JSON with -log-format json
+func (c logCtx) writeLog(level logLevel, message string) {
+	message = strings.TrimSuffix(message, "\n")
+	if !jsonLogs {
+		log.Printf("%-5s %s", levelNames[level], message)
+		return
+	}
+	writeJSONLog(jsonLogLine{Level: strings.ToLower(levelNames[level]), Message: message, File: c.file, Resource: c.resource})
+}
+
+// writeJSONLog writes line as a single JSON object, stamped with the current time
+func writeJSONLog(line jsonLogLine) {
+	line.Time = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(line)
+	if err != nil {
+		log.Printf("%-5s %s", levelNames[levelError], line.Message)
+		return
+	}
+	log.Print(string(data))
+}
+
+// debugf logs a message only at debug level (-verbose or -log-level debug)
+func (c logCtx) debugf(format string, args ...interface{}) { c.logf(levelDebug, format, args...) }
+
+// infof logs a progress message
+func (c logCtx) infof(format string, args ...interface{}) { c.logf(levelInfo, format, args...) }
+
+// warnf logs a problem that does not stop the run, such as a skipped document
+func (c logCtx) warnf(format string, args ...interface{}) {
+	warningsLogged.Add(1)
+	c.logf(levelWarn, format, args...)
+}
+
+// errorf logs a failure affecting a single file or resource and records it for the exit code
+func (c logCtx) errorf(format string, args ...interface{}) {
+	errorsLogged.Add(1)
+	c.logf(levelError, format, args...)
+}
+
+// debugf, infof, warnf and errorf log from the main goroutine, with the logCtx
+// set by setLogContext
+func debugf(format string, args ...interface{}) { currentLogCtx().debugf(format, args...) }
+func infof(format string, args ...interface{})  { currentLogCtx().infof(format, args...) }
+func warnf(format string, args ...interface{})  { currentLogCtx().warnf(format, args...) }
+func errorf(format string, args ...interface{}) { currentLogCtx().errorf(format, args...) }
+
+// cliLogger passes the messages of pkg/compare to the leveled log
+type cliLogger struct{}
+
+func (cliLogger) Debugf(format string, args ...interface{}) { debugf(format, args...) }
+func (cliLogger) Infof(format string, args ...interface{})  { infof(format, args...) }
+
+// exitIfInterrupted exits with exitInterrupted when ctx was cancelled by a signal,
+// so a failure caused by the cancellation is not reported as an error
+func exitIfInterrupted(ctx context.Context) {
+	if ctx.Err() != nil {
+		warnf("Interrupted")
+		os.Exit(exitInterrupted)
+	}
+}
+
+// fatalf logs an error regardless of level and exits with exitError
+func fatalf(format string, args ...interface{}) {
+	currentLogCtx().writeLog(levelError, fmt.Sprintf(format, args...))
+	os.Exit(exitError)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// captureJSONLogs switches to JSON logs at debug level written to the returned
+// buffer until the test ends
+func captureJSONLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	prevJSON, prevLevel, prevFlags := jsonLogs, minLogLevel, log.Flags()
+	jsonLogs, minLogLevel = true, levelDebug
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		jsonLogs, minLogLevel = prevJSON, prevLevel
+		log.SetOutput(os.Stderr)
+		log.SetFlags(prevFlags)
+		setLogContext(logCtx{})
+	})
+	return &out
+}
+
+// TestFetchJSONLogContext fetches with several workers while the main goroutine
+// moves through the resources as the compare loop does, and checks every lookup
+// line names its own file and resource. Run with -race to check the log context
+// for data races.
+func TestFetchJSONLogContext(t *testing.T) {
+	out := captureJSONLogs(t)
+
+	objects := []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}
+	var resources []LocalResource
+	for i := 0; i < 30; i++ {
+		name := fmt.Sprintf("secret-%02d", i)
+		resources = append(resources, testSecret("default", name))
+		objects = append(objects, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	fileOf := func(resource LocalResource) string { return resource.GetName() + ".yaml" }
+	logCtxOf := func(resource LocalResource) logCtx { return resourceLogCtx(fileOf(resource), resource) }
+
+	pool := startFetch(context.Background(), clusterGetter(clientset, time.Second, 1), resources, 8, logCtxOf, nil)
+	for i, resource := range resources {
+		setLogContext(logCtxOf(resource))
+		if result := pool.result(i); result.Err != nil {
+			t.Errorf("%s: %v", resource.GetName(), result.Err)
+		}
+		debugf("Comparing %s", resource.GetName())
+	}
+	pool.wait()
+	setLogContext(logCtx{})
+
+	lookups := 0
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry jsonLogLine
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		var name string
+		switch {
+		case strings.HasPrefix(entry.Message, "Lookup of Secret '"):
+			lookups++
+			name = strings.SplitN(entry.Message, "'", 3)[1]
+		case strings.HasPrefix(entry.Message, "Comparing "):
+			name = strings.TrimPrefix(entry.Message, "Comparing ")
+		default:
+			continue
+		}
+		if want := "Secret default/" + name; entry.Resource != want || entry.File != name+".yaml" {
+			t.Errorf("line %q has file %q and resource %q, want %q and %q", entry.Message, entry.File, entry.Resource, name+".yaml", want)
+		}
+	}
+	if lookups != len(resources) {
+		t.Errorf("logged %d lookups, want %d", lookups, len(resources))
+	}
+}
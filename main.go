@@ -2,295 +2,1603 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1" // Renamed for clarity
 	"k8s.io/client-go/kubernetes"
-	// Uncomment the following line if you need to use in-cluster config
-	// "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"k8s-secret-compare/pkg/compare"
 )
 
-// KubernetesSecret represents the structure of a Kubernetes Secret YAML file
-type KubernetesSecret struct {
-	APIVersion string            `yaml:"apiVersion"`
-	Kind       string            `yaml:"kind"`
-	Metadata   Metadata          `yaml:"metadata"`
-	Type       string            `yaml:"type,omitempty"`
-	StringData map[string]string `yaml:"stringData,omitempty"`
-}
+// The manifest types and the comparison itself live in pkg/compare so other
+// programs can embed them; the aliases keep the CLI code unchanged.
+type (
+	KubernetesSecret = compare.KubernetesSecret
+	KubernetesConfig = compare.KubernetesConfig
+	Metadata         = compare.Metadata
+	LocalResource    = compare.LocalResource
+	SecretDifference = compare.SecretDifference
+	CompareOptions   = compare.Options
+)
 
-// KubernetesConfig represents the structure of a Kubernetes ConfigMap YAML file
-type KubernetesConfig struct {
-	APIVersion string            `yaml:"apiVersion"`
-	Kind       string            `yaml:"kind"`
-	Metadata   Metadata          `yaml:"metadata"`
-	Data       map[string]string `yaml:"data,omitempty"`
+// DeployedData represents the structure of a deployed Kubernetes Secret or ConfigMap
+type DeployedData struct {
+	Type            string
+	Name            string
+	Namespace       string
+	Data            map[string]string
+	BinaryKeys      map[string]bool // keys that came from ConfigMap binaryData
+	SecretType      string          // type of a deployed Secret, e.g. Opaque
+	Immutable       *bool
+	Controller      string // controller owner reference as Kind/name, if any
+	ResourceVersion string // identifies the cluster state the resource was read at
+	Finalizers      []string
+	Labels          map[string]string
+	Annotations     map[string]string
 }
 
-// Metadata holds the metadata information for Kubernetes resources
-type Metadata struct {
-	Name      string `yaml:"name"`
-	Namespace string `yaml:"namespace"`
+func main() {
+	runStart := time.Now()
+
+	// Define command-line flags
+	dirPtr := flag.String("dir", ".", "Comma-separated directories to scan for config and secret YAML files")
+	patternPtr := flag.String("pattern", "*secret*.yaml,*secret*.yml,*config*.yaml,*config*.yml", "Comma-separated glob patterns to identify secret & config YAML files (e.g., \"*secret*.yaml,*secret*.yml\")")
+	verbosePtr := flag.Bool("verbose", false, "Enable verbose logging (same as -log-level debug)")
+	logLevelPtr := flag.String("log-level", "info", "Minimum level of operational logs written to stderr: debug, info, warn or error")
+	logFormatPtr := flag.String("log-format", "text", "Format of operational logs: text or json (one object per line, ending with a summary line)")
+	equivalenceSetPtr := flag.String("equivalence-set", "", "Comma-separated equivalence classes of '='-joined values treated as equal (e.g., \"true=True=1=enabled,false=False=0=disabled\")")
+	ignoreKeysPtr := flag.String("ignore-keys", "", "Comma-separated key names or glob patterns (e.g., \"ca.crt,token-*\") to leave out of the comparison")
+	onlyKeysPtr := flag.String("only-keys", "", "Comma-separated key names or glob patterns to restrict the comparison to (applied before -ignore-keys)")
+	ignoreTrailingNewlinePtr := flag.Bool("ignore-trailing-newline", false, "Ignore a single trailing newline when comparing values")
+	checksumAnnotationPtr := flag.String("checksum-annotation", "", "Compare this annotation of each deployed resource (e.g. checksum/config) with the SHA-256 of its local file, as computed by Helm's sha256sum")
+	compareMetadataPtr := flag.Bool("compare-metadata", false, "Also compare labels and annotations between local and deployed resources")
+	ignoreAnnotationsPtr := flag.String("ignore-annotations", "kubectl.kubernetes.io/last-applied-configuration", "Comma-separated annotation keys or glob patterns to leave out of -compare-metadata")
+	normalizePEMPtr := flag.Bool("normalize-pem", false, "Compare PEM-encoded values (certificates, keys) after re-wrapping them to 64 columns")
+	normalizeWhitespacePtr := flag.Bool("normalize-whitespace", false, "Compare values after converting CRLF to LF and trimming trailing whitespace on each line")
+	showNormalizedPtr := flag.Bool("show-normalized", false, "Show whitespace-normalized values in the report and merge snippets instead of the raw values")
+	finalizersComparePtr := flag.Bool("finalizers-compare", false, "Also compare metadata.finalizers between local and deployed resources")
+	kubeconfigPtr := flag.String("kubeconfig", "", "Path to the kubeconfig file (overrides KUBECONFIG and ~/.kube/config)")
+	kubeconfigDataPtr := flag.String("kubeconfig-data", "", "Raw kubeconfig YAML to use instead of KUBECONFIG and ~/.kube/config (defaults to $"+kubeconfigDataEnv+")")
+	contextPtr := flag.String("context", "", "Kubeconfig context to use (defaults to the current context)")
+	inClusterPtr := flag.Bool("in-cluster", false, "Use the in-cluster ServiceAccount config (auto-detected when no kubeconfig or context is given)")
+	asPtr := flag.String("as", "", "User or service account to impersonate (e.g. system:serviceaccount:ops:drift-check)")
+	asGroupPtr := flag.String("as-group", "", "Comma-separated groups to impersonate")
+	tokenPtr := flag.String("token", "", "Bearer token to authenticate with instead of the kubeconfig credentials")
+	namespacePtr := flag.String("namespace", "", "Namespace to look up resources in, overriding the namespace in the manifests; '*' searches all namespaces by name")
+	namespacesPtr := flag.String("namespaces", "", "Comma-separated namespaces to compare every local resource against, overriding the namespace in the manifests (e.g. one per tenant)")
+	maskPtr := flag.Bool("mask", true, "Mask values in the output (defaults to true for Secrets and false for ConfigMaps)")
+	concurrencyPtr := flag.Int("concurrency", 8, "Number of deployed resources to fetch in parallel")
+	timeoutPtr := flag.Duration("timeout", 30*time.Second, "Timeout for each deployed resource lookup")
+	qpsPtr := flag.Float64("qps", defaultQPS, "Maximum API requests per second to the cluster (client-side rate limit)")
+	burstPtr := flag.Int("burst", defaultBurst, "Maximum API requests sent at once above -qps")
+	retriesPtr := flag.Int("retries", 3, "Maximum attempts for a deployed resource lookup that fails with a transient API error")
+	filterNamePtr := flag.String("filter-name", "", "Comma-separated resource names or glob patterns to check")
+	filterNamespacePtr := flag.String("filter-namespace", "", "Comma-separated namespaces or glob patterns to check")
+	filterKindPtr := flag.String("filter-kind", "", "Comma-separated kinds to check (Secret, ConfigMap)")
+	mappingPtr := flag.String("mapping", "", "YAML or JSON file mapping file paths or manifest names to the name and namespace to look up in the cluster")
+	maxFilesPtr := flag.Int("max-files", 0, "Abort before comparing when more than this many files match (0 means no limit)")
+	recursivePtr := flag.Bool("recursive", false, "Scan subdirectories of -dir, skipping hidden directories such as .git")
+	compareToPtr := flag.String("compare-to", "", "File or directory of manifests to compare against instead of the cluster")
+	writePatchPtr := flag.String("write-patch", "", "Write a multi-document YAML file with the keys to change locally to match the cluster")
+	forcePtr := flag.Bool("force", false, "Overwrite an existing -write-patch file")
+	exitZeroPtr := flag.Bool("exit-zero", false, "Exit with code 0 even when differences are found (errors still exit with code 2)")
+	colorPtr := flag.String("color", "auto", "Colorize the text report: auto, always or never (auto honors NO_COLOR and disables color when piped)")
+	formatPtr := flag.String("format", "yaml", "Format of local files: yaml or dotenv (KEY=value files compared against the Secret named by -dotenv-name)")
+	dotenvNamePtr := flag.String("dotenv-name", "", "Name of the deployed Secret to compare dotenv files against (requires -namespace)")
+	templateValuesPtr := flag.String("template-values", "", "YAML values file used to render local files ending in .tmpl with Go text/template")
+	lintPtr := flag.Bool("lint", false, "Only validate the local files (names, namespaces, data, base64, apiVersion) without connecting to a cluster")
+	strictPtr := flag.Bool("strict", false, "Skip Secrets and ConfigMaps whose apiVersion is not v1 instead of only warning about them")
+	changedSincePtr := flag.String("changed-since", "", "Only check matching files that changed since this git ref (e.g. origin/main)")
+	watchPtr := flag.Bool("watch", false, "Re-run the comparison whenever a matched file changes, until interrupted with Ctrl-C")
+	var fileFlags stringList
+	flag.Var(&fileFlags, "file", "File to compare, bypassing -dir and -pattern (repeatable)")
+	stdinPtr := flag.Bool("stdin", false, "Read a multi-document YAML stream from stdin (e.g. helm template output) instead of scanning -dir")
+	sopsPtr := flag.Bool("sops", false, "Decrypt local files with 'sops -d' before parsing them (plaintext is kept in memory only)")
+	selectorPtr := flag.String("selector", "", "Label selector (e.g. app=web) listing the deployed Secrets and ConfigMaps to audit against the local files")
+	sourceContextPtr := flag.String("source-context", "", "Kubeconfig context of a source cluster to compare against -target-context instead of local files (requires -namespace)")
+	targetContextPtr := flag.String("target-context", "", "Kubeconfig context of the target cluster compared against -source-context")
+	failOnDuplicatesPtr := flag.Bool("fail-on-duplicates", false, "Exit with an error when a resource is defined more than once across the scanned files")
+	printKubectlPtr := flag.Bool("print-kubectl", false, "Print a kubectl patch command per resource that pushes the local values of differing keys to the cluster")
+	detectOrphansPtr := flag.Bool("detect-orphans", false, "Report deployed Secrets and ConfigMaps in the namespaces of the local files that have no local manifest")
+	minResourceVersionPtr := flag.String("min-resource-version", "", "Warn about deployed resources whose resourceVersion is older than this one")
+	transformCmdPtr := flag.String("transform-cmd", "", "Command that every local and deployed value is piped through (stdin to stdout) before comparing")
+	expandEnvPtr := flag.Bool("expand-env", false, "Expand ${VAR} and $VAR references in local values from the environment before comparing")
+	ignoreEmptyPtr := flag.Bool("ignore-empty", false, "Treat a key with an empty value as equal to a missing key")
+	semanticPtr := flag.Bool("semantic", false, "Compare values that parse as YAML or JSON documents by content, listing the nested fields that changed")
+	dumpDirPtr := flag.String("dump-dir", "", "Write the local and deployed value of every differing key to files under this directory (requires unmasked values; never cleaned up)")
+	managedMarkersPtr := flag.String("managed-markers", defaultManagedMarkers, "Comma-separated labels or annotations (key, key=value or key!=value; keys may be globs) marking a deployed resource as operator-managed")
+	skipManagedPtr := flag.Bool("skip-managed", false, "Do not let differences in operator-managed resources affect the exit code")
+	checkPtr := flag.Bool("check", false, "Before comparing, verify API connectivity and the permission to read the resources to compare, and exit if either fails")
+	failOnMissingPtr := flag.Bool("fail-on-missing", false, "Count a local resource that is not deployed as a difference instead of only warning about it")
+	failFastPtr := flag.Bool("fail-fast", false, "Stop at the first resource with differences and exit non-zero")
+	diffContextPtr := flag.Int("diff-context", 3, "Number of unchanged lines shown around each change in -output diff")
+	snippetIndentPtr := flag.Int("snippet-indent", 2, "Spaces per indentation level in merge snippets and -write-patch files (2-9)")
+	maxValuePrintPtr := flag.Int("max-value-print", 16384, "Summarize values longer than this many bytes by size and hash in the text report (0 prints every value in full)")
+	quietPtr := flag.Bool("quiet", false, "Print only the final summary line of the text report; the exit code is unchanged")
+	onlyDiffPtr := flag.Bool("only-diff", false, "Only print resources with differences in the text report (matching resources still count in the summary)")
+	directionPtr := flag.String("direction", "cluster-to-local", "Framing of the text report: cluster-to-local (make local match the cluster) or local-to-cluster (what applying local files would change)")
+	outputPtr := flag.String("output", "text", "Comma-separated output formats: text, diff, json, json-summary, junit, markdown or ndjson-events")
+	warnEncodingPtr := flag.Bool("warn-encoding", false, "Warn about Secret data keys whose decoded value matches but whose base64 is not canonical (padding or line wrapping)")
+	outputFilePtr := flag.String("output-file", "", "Comma-separated files, one per -output format ('-' for stdout), or one name containing {format}; parent directories are created and existing files truncated")
+	teePtr := flag.Bool("tee", false, "With -output-file, also write the report to stdout")
+	configPtr := flag.String("config", "", "YAML file of flag defaults keyed by flag name (default .secret-compare.yaml in the working directory, if present)")
+	flag.Parse()
+	configFile, err := loadConfigFile(flag.CommandLine, *configPtr)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	// Set up logging; -verbose is kept as a shorthand for -log-level debug.
+	// Logs always go to stderr so the report on stdout can be piped (e.g. into jq).
+	log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	level, err := parseLogLevel(*logLevelPtr)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *verbosePtr {
+		level = levelDebug
+	}
+	minLogLevel = level
+	jsonLogs, err = parseLogFormat(*logFormatPtr)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if minLogLevel == levelDebug && !jsonLogs {
+		log.SetFlags(log.Ldate | log.Ltime)
+	}
+	if configFile != "" {
+		debugf("Loaded flag defaults from %s", configFile)
+	}
+
+	colorEnabled, err = resolveColor(*colorPtr)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	formats := splitList(*outputPtr)
+	targets, err := outputTargets(formats, splitList(*outputFilePtr))
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *teePtr && (*outputFilePtr == "" || contains(targets, "")) {
+		warnf("-tee has no effect unless every -output format is written to a file")
+	}
+	writers := make([]io.Writer, len(formats))
+	for i, target := range targets {
+		if target == "" {
+			writers[i] = os.Stdout
+			continue
+		}
+		writers[i], err = openOutputFile(target, *teePtr && !contains(targets, ""))
+		if err != nil {
+			fatalf("%v", err)
+		}
+		debugf("Writing the %s report to %s", formats[i], target)
+	}
+
+	// Secrets are masked by default; an explicit -mask applies to every kind
+	maskExplicit, patternExplicit, dirExplicit, logLevelExplicit := false, false, false, false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "log-level":
+			logLevelExplicit = true
+		case "mask":
+			maskExplicit = true
+		case "pattern":
+			patternExplicit = true
+		case "dir":
+			dirExplicit = true
+		}
+	})
+	// -quiet also hides progress logs unless a log level was asked for
+	if *quietPtr && !logLevelExplicit && !*verbosePtr {
+		minLogLevel = levelWarn
+	}
+	shouldMask := func(kind string) bool {
+		if maskExplicit {
+			return *maskPtr
+		}
+		return kind == "Secret"
+	}
+
+	var textOpts TextOptions
+	textOpts.OnlyDiff = *onlyDiffPtr
+	textOpts.MaxValuePrint = *maxValuePrintPtr
+	textOpts.DiffContext = *diffContextPtr
+	textOpts.SnippetIndent = *snippetIndentPtr
+	if *diffContextPtr < 0 {
+		fatalf("-diff-context must not be negative")
+	}
+	if *snippetIndentPtr < 2 || *snippetIndentPtr > 9 {
+		fatalf("-snippet-indent must be between 2 and 9")
+	}
+	textOpts.ShowMatching = minLogLevel == levelDebug
+	switch *directionPtr {
+	case "cluster-to-local":
+	case "local-to-cluster":
+		textOpts.LocalToCluster = true
+	default:
+		fatalf("Unsupported direction '%s' (expected cluster-to-local or local-to-cluster)", *directionPtr)
+	}
+
+	var reporters []Reporter
+	var events *EventWriter
+	hasTextReport := false
+	for i, format := range formats {
+		switch format {
+		case "text", "diff":
+			opts := textOpts
+			opts.Unified = format == "diff"
+			hasTextReport = true
+			// Keep escape codes out of an archived report unless asked for
+			if targets[i] != "" && *colorPtr == "auto" {
+				colorEnabled = false
+			}
+			reporters = append(reporters, &textReporter{w: writers[i], opts: opts, quiet: *quietPtr, printKubectl: *printKubectlPtr})
+		case "json":
+			reporters = append(reporters, &jsonReporter{w: writers[i], report: JSONReport{SkipManaged: *skipManagedPtr}})
+		case "json-summary":
+			reporters = append(reporters, &jsonSummaryReporter{w: writers[i], report: JSONReport{SkipManaged: *skipManagedPtr}})
+		case "junit":
+			reporters = append(reporters, &junitReporter{w: writers[i], suite: NewJUnitReport()})
+		case "markdown":
+			reporters = append(reporters, &markdownReporter{w: writers[i], report: MarkdownReport{SnippetIndent: *snippetIndentPtr}})
+		case "ndjson-events":
+			events = NewEventWriter(writers[i])
+			reporters = append(reporters, &eventReporter{events: events})
+		}
+	}
+
+	if jsonLogs {
+		reporters = append(reporters, &logSummaryReporter{summary: jsonSummaryReporter{report: JSONReport{SkipManaged: *skipManagedPtr}}})
+	}
+
+	targetNamespaces := splitList(*namespacesPtr)
+	if len(targetNamespaces) > 0 && *namespacePtr != "" {
+		fatalf("-namespace and -namespaces cannot be combined")
+	}
+	parseOpts := ParseOptions{
+		Namespace:  *namespacePtr,
+		Format:     *formatPtr,
+		DotenvName: *dotenvNamePtr,
+		Sops:       *sopsPtr,
+		Strict:     *strictPtr || *lintPtr,
+	}
+	if len(targetNamespaces) > 0 {
+		// Parsed into the first namespace, then copied into every other one
+		parseOpts.Namespace = targetNamespaces[0]
+	}
+	if *templateValuesPtr != "" {
+		parseOpts.TemplateValues, err = loadTemplateValues(*templateValuesPtr)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		// Also pick up templates of the default manifest patterns
+		if !patternExplicit {
+			*patternPtr += ",*secret*.yaml.tmpl,*secret*.yml.tmpl,*config*.yaml.tmpl,*config*.yml.tmpl"
+		}
+	}
+	switch *formatPtr {
+	case "yaml":
+	case "dotenv":
+		if *dotenvNamePtr == "" || parseOpts.Namespace == "" {
+			fatalf("-format dotenv requires -dotenv-name and -namespace (or -namespaces)")
+		}
+		// The default patterns only match manifests
+		if !patternExplicit {
+			*patternPtr = ".env,*.env"
+		}
+	default:
+		fatalf("Unsupported format '%s' (expected yaml or dotenv)", *formatPtr)
+	}
+
+	if *watchPtr {
+		if *stdinPtr || *sourceContextPtr != "" {
+			fatalf("-watch needs local files and cannot be combined with -stdin or -source-context")
+		}
+		listFiles := func() ([]string, error) {
+			if len(fileFlags) > 0 {
+				return fileFlags, nil
+			}
+			return findFilesInDirs(splitList(*dirPtr), *patternPtr, *recursivePtr)
+		}
+		// -watch=false wins over a config file's watch, so the runs don't watch themselves
+		os.Exit(watchAndRerun(listFiles, append([]string{"-watch=false"}, withoutWatchFlag(os.Args[1:])...)))
+	}
+	compareOpts := CompareOptions{
+		Equivalences:          parseEquivalenceSets(*equivalenceSetPtr),
+		OnlyKeys:              splitList(*onlyKeysPtr),
+		IgnoreKeys:            splitList(*ignoreKeysPtr),
+		IgnoreTrailingNewline: *ignoreTrailingNewlinePtr,
+		NormalizeWhitespace:   *normalizeWhitespacePtr,
+		NormalizePEM:          *normalizePEMPtr,
+		ShowNormalized:        *showNormalizedPtr,
+		Semantic:              *semanticPtr,
+		IgnoreEmpty:           *ignoreEmptyPtr,
+		Logger:                cliLogger{},
+	}
+
+	twoClusters := *sourceContextPtr != "" || *targetContextPtr != ""
+	if twoClusters {
+		if *sourceContextPtr == "" || *targetContextPtr == "" {
+			fatalf("-source-context and -target-context must be set together")
+		}
+		if *namespacePtr == "" {
+			fatalf("-source-context and -target-context require -namespace")
+		}
+		if len(targetNamespaces) > 0 {
+			fatalf("-namespaces cannot be combined with -source-context and -target-context")
+		}
+		if *compareToPtr != "" {
+			fatalf("-compare-to cannot be combined with -source-context and -target-context")
+		}
+	}
+
+	if *selectorPtr != "" && (twoClusters || *compareToPtr != "" || len(targetNamespaces) > 0) {
+		fatalf("-selector cannot be combined with -compare-to, -source-context or -namespaces")
+	}
+	if *detectOrphansPtr && (*compareToPtr != "" || *selectorPtr != "") {
+		fatalf("-detect-orphans needs a cluster and cannot be combined with -compare-to or -selector (which already reports deployed resources without a local file)")
+	}
+
+	// Read here rather than as the flag default so -h never prints the credentials
+	kubeconfigData := *kubeconfigDataPtr
+	if kubeconfigData == "" {
+		kubeconfigData = os.Getenv(kubeconfigDataEnv)
+	}
+	// Ctrl-C or SIGTERM cancels the lookups in flight; the results so far are still reported.
+	// A second signal stops the process immediately.
+	runCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-runCtx.Done()
+		stopSignals()
+	}()
+
+	var getter resourceGetter
+	var sourceClientset kubernetes.Interface
+	var deployedClientset kubernetes.Interface // cluster holding the deployed side, if any
+	var selected selectedResources
+	switch {
+	case twoClusters:
+		// The source cluster stands in for the local files, the target for the deployed side
+		sourceClientset, _, err = getKubernetesClient(ClientOptions{Kubeconfig: *kubeconfigPtr, KubeconfigData: kubeconfigData, Context: *sourceContextPtr, QPS: float32(*qpsPtr), Burst: *burstPtr})
+		if err != nil {
+			fatalf("Failed to create Kubernetes client for source context '%s': %v", *sourceContextPtr, err)
+		}
+		targetClientset, _, err := getKubernetesClient(ClientOptions{Kubeconfig: *kubeconfigPtr, KubeconfigData: kubeconfigData, Context: *targetContextPtr, QPS: float32(*qpsPtr), Burst: *burstPtr})
+		if err != nil {
+			fatalf("Failed to create Kubernetes client for target context '%s': %v", *targetContextPtr, err)
+		}
+		deployedClientset = targetClientset
+		getter = clusterGetter(targetClientset, *timeoutPtr, *retriesPtr)
+	case *lintPtr:
+		// Only the local files are validated below; nothing is compared
+	case *compareToPtr != "":
+		// Compare against a second set of local files; no cluster access needed
+		getter, err = localGetter(*compareToPtr, *patternPtr, *recursivePtr, parseOpts)
+		if err != nil {
+			fatalf("Failed to load comparison files: %v", err)
+		}
+	default:
+		// Create Kubernetes client
+		clientset, contextNamespace, err := getKubernetesClient(ClientOptions{
+			Kubeconfig:     *kubeconfigPtr,
+			KubeconfigData: kubeconfigData,
+			Context:        *contextPtr,
+			InCluster:      *inClusterPtr,
+			As:             *asPtr,
+			AsGroups:       splitList(*asGroupPtr),
+			Token:          *tokenPtr,
+			QPS:            float32(*qpsPtr),
+			Burst:          *burstPtr,
+		})
+		if err != nil {
+			fatalf("Failed to create Kubernetes client: %v", err)
+		}
+		// Manifests without a namespace fall back to the context's, like kubectl
+		parseOpts.DefaultNamespace = contextNamespace
+		deployedClientset = clientset
+		getter = clusterGetter(clientset, *timeoutPtr, *retriesPtr)
+
+		if *selectorPtr != "" {
+			namespace := *namespacePtr
+			if namespace == "" {
+				namespace = contextNamespace
+			}
+			if namespace == anyNamespace {
+				namespace = metav1.NamespaceAll
+			}
+			ctx, cancel := context.WithTimeout(runCtx, *timeoutPtr)
+			selected, err = listSelected(ctx, clientset, namespace, *selectorPtr)
+			cancel()
+			if err != nil {
+				exitIfInterrupted(runCtx)
+				fatalf("Failed to list resources matching selector '%s': %v", *selectorPtr, err)
+			}
+			infof("Found %d resources matching selector '%s' in namespace '%s'", len(selected), *selectorPtr, namespace)
+			getter = selected.getter()
+		}
+	}
+
+	if *stdinPtr && *sopsPtr {
+		fatalf("-sops cannot be combined with -stdin; decrypt the stream before piping it")
+	}
+	if *stdinPtr && *checksumAnnotationPtr != "" {
+		fatalf("-checksum-annotation needs local files and cannot be combined with -stdin")
+	}
+
+	var files []string
+	var localResources []LocalResource
+	if sourceClientset != nil {
+		// Discover resources from the source cluster instead of files
+		ctx, cancel := context.WithTimeout(runCtx, *timeoutPtr)
+		localResources, err = listClusterResources(ctx, sourceClientset, *namespacePtr)
+		cancel()
+		if err != nil {
+			exitIfInterrupted(runCtx)
+			fatalf("Failed to list resources in source context '%s': %v", *sourceContextPtr, err)
+		}
+		infof("Found %d resources in namespace '%s' of source context '%s'\n", len(localResources), *namespacePtr, *sourceContextPtr)
+	} else if len(fileFlags) > 0 {
+		// Explicit files bypass -dir and -pattern entirely
+		if dirExplicit || patternExplicit {
+			warnf("-dir and -pattern are ignored because -file is set")
+		}
+		for _, file := range fileFlags {
+			info, err := os.Stat(file)
+			if err != nil {
+				fatalf("File '%s' given with -file cannot be read: %v", file, err)
+			}
+			if info.IsDir() {
+				fatalf("File '%s' given with -file is a directory (use -dir instead)", file)
+			}
+		}
+		files = fileFlags
+	} else if *stdinPtr {
+		// A single multi-document stream, e.g. piped from helm template
+		files = []string{stdinPath}
+	} else {
+		// Process file patterns
+		files, err = findFilesInDirs(splitList(*dirPtr), *patternPtr, *recursivePtr)
+		if err != nil {
+			fatalf("Error finding files: %v", err)
+		}
+
+		if *changedSincePtr != "" && len(files) > 0 {
+			changed := make(map[string]bool)
+			for _, dir := range splitList(*dirPtr) {
+				dirChanged, err := changedFiles(dir, *changedSincePtr)
+				if err != nil {
+					fatalf("Failed to list files changed since '%s': %v", *changedSincePtr, err)
+				}
+				for file := range dirChanged {
+					changed[file] = true
+				}
+			}
+			matched := len(files)
+			files, err = filterChanged(files, changed)
+			if err != nil {
+				fatalf("%v", err)
+			}
+			infof("%d of %d matching files changed since '%s'", len(files), matched, *changedSincePtr)
+		}
+
+		if len(files) == 0 {
+			infof("No YAML files matching the specified patterns were found in the directory.")
+			return
+		}
+	}
+
+	if *maxFilesPtr > 0 && len(files) > *maxFilesPtr {
+		fatalf("%d files match, more than -max-files %d; check -dir and -pattern, or raise -max-files", len(files), *maxFilesPtr)
+	}
+
+	var mapping *resourceMapping
+	if *mappingPtr != "" {
+		mapping, err = loadMapping(*mappingPtr)
+		if err != nil {
+			fatalf("Failed to load -mapping: %v", err)
+		}
+	}
+
+	// Parse every file up front so lookups can be dispatched concurrently
+	parseStart := time.Now()
+	warningsBefore, errorsBefore := warningsLogged.Load(), errorsLogged.Load()
+	definedIn := make(map[string][]string)      // resource key to the files defining it
+	checksums := make(map[LocalResource]string) // checksum of the file defining each resource, with -checksum-annotation
+	for _, file := range files {
+		setLogContext(logCtx{file: file})
+		infof("Processing file: %s\n", filepath.Base(file))
+		fileResources, err := parseLocalFile(file, parseOpts)
+		if err != nil {
+			errorf("Error parsing YAML file '%s': %v\n", filepath.Base(file), err)
+			continue
+		}
+		if mapping != nil {
+			fileResources = mapping.apply(fileResources, file)
+		}
+		if events != nil {
+			events.Emit("file_parsed", map[string]interface{}{
+				"file":      file,
+				"resources": len(fileResources),
+			})
+		}
+		if *checksumAnnotationPtr != "" && file != stdinPath {
+			checksum, err := fileChecksum(file, parseOpts)
+			if err != nil {
+				errorf("Error computing the checksum of file '%s': %v", filepath.Base(file), err)
+			}
+			for _, resource := range fileResources {
+				checksums[resource] = checksum
+			}
+		}
+		for _, resource := range fileResources {
+			key := resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())
+			definedIn[key] = append(definedIn[key], file)
+		}
+		localResources = append(localResources, fileResources...)
+	}
+
+	setLogContext(logCtx{})
+	debugf("Parsed %d files in %s", len(files), time.Since(parseStart).Round(time.Millisecond))
+
+	if duplicates := reportDuplicates(definedIn); duplicates > 0 && *failOnDuplicatesPtr {
+		fatalf("Found %d resources defined more than once (-fail-on-duplicates)", duplicates)
+	}
+
+	if *lintPtr {
+		errCount, warnCount := errorsLogged.Load()-errorsBefore, warningsLogged.Load()-warningsBefore
+		if errCount == 0 && warnCount == 0 {
+			fmt.Printf("Lint: %d resources in %d files are valid.\n", len(localResources), len(files))
+			os.Exit(exitMatch)
+		}
+		fmt.Printf("Lint: %d errors and %d warnings in %d files (%d valid resources).\n", errCount, warnCount, len(files), len(localResources))
+		if errCount > 0 {
+			os.Exit(exitError)
+		}
+		os.Exit(exitDifferences)
+	}
+
+	if len(targetNamespaces) > 0 {
+		manifests := localResources
+		localResources = expandNamespaces(localResources, targetNamespaces)
+		// The copies are grouped by namespace in manifest order; they share the file checksum
+		for i, resource := range localResources {
+			if checksum, ok := checksums[manifests[i%len(manifests)]]; ok {
+				checksums[resource] = checksum
+			}
+		}
+		infof("Comparing %d resources in %d namespaces", len(localResources), len(targetNamespaces))
+	}
+
+	filter := ResourceFilter{
+		Names:      splitList(*filterNamePtr),
+		Namespaces: splitList(*filterNamespacePtr),
+		Kinds:      splitList(*filterKindPtr),
+	}
+	localResources = filterResources(localResources, filter)
+
+	// Resources in namespace '*' are looked up by name in every namespace and
+	// compared against each match
+	wildcardFound := make(selectedResources)
+	resolved := make([]LocalResource, 0, len(localResources))
+	for _, resource := range localResources {
+		if resource.GetNamespace() != anyNamespace {
+			resolved = append(resolved, resource)
+			continue
+		}
+		if deployedClientset == nil {
+			fatalf("%s '%s' uses namespace '%s', which needs a cluster to look it up in", resource.GetKind(), resource.GetName(), anyNamespace)
+		}
+		ctx, cancel := context.WithTimeout(runCtx, *timeoutPtr)
+		found, err := findInAllNamespaces(ctx, deployedClientset, resource)
+		cancel()
+		if err != nil {
+			exitIfInterrupted(runCtx)
+			fatalf("Failed to look up %s '%s' in all namespaces: %v", resource.GetKind(), resource.GetName(), err)
+		}
+		switch len(found) {
+		case 0:
+			// Kept in namespace '*' so it is reported as not found
+			warnf("%s '%s' was not found in any namespace", resource.GetKind(), resource.GetName())
+			resolved = append(resolved, resource)
+			continue
+		case 1:
+			infof("Found %s '%s' in namespace '%s'", resource.GetKind(), resource.GetName(), found[0].Namespace)
+		default:
+			namespaces := make([]string, len(found))
+			for i, deployed := range found {
+				namespaces[i] = deployed.Namespace
+			}
+			infof("Found %s '%s' in %d namespaces (%s); comparing against each", resource.GetKind(), resource.GetName(), len(found), strings.Join(namespaces, ", "))
+		}
+		for _, deployed := range found {
+			copied := withNamespace(resource, deployed.Namespace)
+			if checksum, ok := checksums[resource]; ok {
+				checksums[copied] = checksum
+			}
+			wildcardFound[resourceKey(resource.GetKind(), deployed.Namespace, resource.GetName())] = deployed
+			resolved = append(resolved, copied)
+		}
+	}
+	localResources = resolved
+	if len(wildcardFound) > 0 {
+		getter = wildcardGetter(wildcardFound, getter)
+	}
+
+	// Resources found in no namespace have nothing to check or list
+	clusterResources := withoutUnresolved(localResources)
+
+	if *checkPtr && deployedClientset != nil {
+		verbs := []string{"get"}
+		if *detectOrphansPtr {
+			verbs = append(verbs, "list")
+		}
+		if err := preflightCheck(runCtx, deployedClientset, clusterResources, verbs, *timeoutPtr); err != nil {
+			exitIfInterrupted(runCtx)
+			fatalf("Pre-flight check failed: %v", err)
+		}
+		infof("Pre-flight check passed")
+	}
+
+	var orphans []string
+	if *detectOrphansPtr {
+		orphans, err = findOrphans(runCtx, deployedClientset, clusterResources, filter, *timeoutPtr)
+		if err != nil {
+			exitIfInterrupted(runCtx)
+			fatalf("Failed to detect orphaned resources: %v", err)
+		}
+		debugf("Found %d deployed resources without a local manifest", len(orphans))
+	}
+
+	// With -selector, the labeled deployed resources decide what is compared
+	var unmatchedDeployed []unmatchedResource
+	if selected != nil {
+		localResources, unmatchedDeployed = selected.match(localResources)
+	}
+
+	var onFetched func(LocalResource, fetchResult)
+	if events != nil {
+		onFetched = func(resource LocalResource, result fetchResult) {
+			events.Emit("resource_fetched", map[string]interface{}{
+				"kind":        resource.GetKind(),
+				"name":        resource.GetName(),
+				"namespace":   resource.GetNamespace(),
+				"found":       result.Err == nil && result.Deployed != nil,
+				"duration_ms": result.Duration.Milliseconds(),
+			})
+		}
+	}
+	// Lookups run in the background while results are compared in order, so
+	// -fail-fast can cancel the lookups not started yet
+	fetchStart := time.Now()
+	fetchCtx, cancelFetch := context.WithCancel(runCtx)
+	// Log lines name the first file defining a resource. Lookups log with the
+	// resource they are for, not the one being compared.
+	logCtxOf := func(resource LocalResource) logCtx {
+		var file string
+		if sources := definedIn[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())]; len(sources) > 0 {
+			file = sources[0]
+		}
+		return resourceLogCtx(file, resource)
+	}
+	pool := startFetch(fetchCtx, cachedGetter(getter), localResources, *concurrencyPtr, logCtxOf, onFetched)
+
+	// Variable to track if any differences were found across all files
+	var globalDifferencesFound bool = false
+	var patches []string
+	stats := RunStats{Checked: len(localResources), Namespaces: targetNamespaces}
+	drifted := make(map[string]bool) // namespaces with differences
+
+	managedMarkers := parseManagedMarkers(*managedMarkersPtr)
+
+	var transformer *valueTransformer
+	if *transformCmdPtr != "" {
+		transformer, err = newValueTransformer(*transformCmdPtr)
+		if err != nil {
+			fatalf("Invalid -transform-cmd: %v", err)
+		}
+	}
+
+	// compareResource compares a local resource with its deployed counterpart
+	compareResource := func(resource LocalResource, deployed *DeployedData) ComparisonResult {
+		// Use unified comparison logic.
+		resourceOpts := optionsForResource(compareOpts, resource)
+		localData, deployedData := resource.GetLocalData(), deployed.Data
+		if *expandEnvPtr {
+			localData = expandEnvValues(localData, resource)
+		}
+		rawLocal, rawDeployed := localData, deployedData
+		if transformer != nil {
+			localData, deployedData = transformer.transformData(localData, deployedData, resource)
+		}
+		differences := compare.Data(localData, deployedData, resourceOpts)
+		if resource.GetKind() == "Secret" && deployed.SecretType == string(corev1.SecretTypeTLS) {
+			differences = compareTLSCertificates(differences)
+		}
+		if resource.GetKind() == "Secret" && deployed.SecretType == string(corev1.SecretTypeDockerConfigJson) {
+			differences = compareDockerConfigs(differences)
+		}
+		if transformer != nil {
+			// Transformed values only decide equality; everything reported shows the raw values
+			differences = withRawValues(differences, rawLocal, rawDeployed, resourceOpts)
+		}
+		if secret, ok := resource.(*KubernetesSecret); ok && *warnEncodingPtr {
+			warnNonCanonicalEncoding(secret, deployed, differences, resourceOpts)
+		}
+		localBinary := resource.GetBinaryKeys()
+		for i := range differences {
+			key := differences[i].Key
+			differences[i].Binary = localBinary[key] || deployed.BinaryKeys[key] ||
+				isBinaryValue(differences[i].Local) || isBinaryValue(differences[i].Deployed)
+		}
+		result := ComparisonResult{
+			Kind:        resource.GetKind(),
+			Name:        resource.GetName(),
+			Namespace:   resource.GetNamespace(),
+			MergeField:  resource.GetMergeField(),
+			Mask:        shouldMask(resource.GetKind()),
+			Differences: differences,
+		}
+		matching := matchingKeys(localData, deployedData, differences, resourceOpts)
+		result.MatchingKeys = len(matching)
+		if textOpts.ShowMatching {
+			result.Matching = make(map[string]string)
+			for _, key := range matching {
+				result.Matching[key] = rawDeployed[key]
+			}
+		}
+		if *finalizersComparePtr {
+			result.FinalizersOnlyInLocal, result.FinalizersOnlyInDeployed = compareFinalizers(resource.GetFinalizers(), deployed.Finalizers)
+		}
+		if resource.GetKind() == "Secret" {
+			if diff := compareSecretType(resource.GetType(), deployed.SecretType); diff != nil {
+				result.FieldDifferences = append(result.FieldDifferences, *diff)
+			}
+		}
+		if diff := compareImmutable(resource.GetImmutable(), deployed.Immutable); diff != nil {
+			result.FieldDifferences = append(result.FieldDifferences, *diff)
+		}
+		if checksum := checksums[resource]; checksum != "" {
+			if diff := compareChecksumAnnotation(*checksumAnnotationPtr, checksum, deployed); diff != nil {
+				result.FieldDifferences = append(result.FieldDifferences, *diff)
+			}
+		}
+		if *compareMetadataPtr {
+			result.LabelDifferences, result.AnnotationDifferences = compareMetadata(resource, deployed, splitList(*ignoreAnnotationsPtr))
+		}
+		return result
+	}
+
+	// Process each local resource in file order
+	interrupted := 0 // lookups cancelled by a signal
+	for i, resource := range localResources {
+		setLogContext(logCtxOf(resource))
+		fetched := pool.result(i)
+		deployed, err := fetched.Deployed, fetched.Err
+		if err != nil && runCtx.Err() != nil {
+			interrupted++
+			continue
+		}
+		if err != nil && !isNamespaceNotFound(err) {
+			errorf("Error retrieving deployed %s '%s' in namespace '%s': %v\n", resource.GetKind(), resource.GetName(), resource.GetNamespace(), err)
+			stats.Errors++
+			continue
+		}
+		var result ComparisonResult
+		if deployed == nil {
+			if isNamespaceNotFound(err) {
+				warnf("Deployed %s '%s' not found: namespace '%s' does not exist in the cluster (check metadata.namespace or -namespace).\n", resource.GetKind(), resource.GetName(), resource.GetNamespace())
+			} else {
+				warnf("Deployed %s '%s' in namespace '%s' not found.\n", resource.GetKind(), resource.GetName(), resource.GetNamespace())
+			}
+			stats.NotFound++
+			if !*failOnMissingPtr {
+				continue
+			}
+			result = ComparisonResult{
+				Kind:        resource.GetKind(),
+				Name:        resource.GetName(),
+				Namespace:   resource.GetNamespace(),
+				MergeField:  resource.GetMergeField(),
+				Mask:        shouldMask(resource.GetKind()),
+				NotDeployed: true,
+			}
+		} else {
+			result = compareResource(resource, deployed)
+			result.ResourceVersion = deployed.ResourceVersion
+			stats.Add(result)
+			if *minResourceVersionPtr != "" && olderResourceVersion(deployed.ResourceVersion, *minResourceVersionPtr) {
+				warnf("Deployed %s '%s' in namespace '%s' is at resourceVersion %s, older than -min-resource-version %s; the cluster may not have caught up yet", resource.GetKind(), resource.GetName(), resource.GetNamespace(), deployed.ResourceVersion, *minResourceVersionPtr)
+			}
+			if manager := managedBy(deployed, managedMarkers); manager != "" {
+				result.Managed = true
+				warnf("Deployed %s '%s' in namespace '%s' appears to be operator-managed (%s); drift against the local file is expected", resource.GetKind(), resource.GetName(), resource.GetNamespace(), manager)
+			}
+		}
+
+		if *dumpDirPtr != "" && len(result.Differences) > 0 {
+			if result.Mask {
+				infof("Not dumping values of %s '%s' in namespace '%s' while they are masked (use -mask=false)", result.Kind, result.Name, result.Namespace)
+			} else if result.DumpDir, err = dumpValues(*dumpDirPtr, result); err != nil {
+				errorf("Failed to dump values of %s '%s' in namespace '%s': %v", result.Kind, result.Name, result.Namespace, err)
+			}
+		}
+		if *writePatchPtr != "" {
+			if patch := renderPatch(result, *snippetIndentPtr); patch != "" {
+				patches = append(patches, patch)
+			}
+		}
+		if result.HasDifferences() && result.Managed && *skipManagedPtr {
+			stats.ManagedSkipped++
+		} else if result.HasDifferences() {
+			globalDifferencesFound = true
+			drifted[result.Namespace] = true
+		}
+
+		for _, reporter := range reporters {
+			reporter.AddResult(result)
+		}
+
+		if *failFastPtr && result.HasDifferences() {
+			cancelFetch()
+			if skipped := len(localResources) - i - 1; skipped > 0 {
+				infof("Stopping at the first resource with differences, skipping %d remaining (-fail-fast)", skipped)
+			}
+			stats.Checked = i + 1
+			break
+		}
+	}
+
+	setLogContext(logCtx{})
+	lookups := pool.wait()
+	cancelFetch()
+	debugf("Fetched %d deployed resources in %s", lookups, time.Since(fetchStart).Round(time.Millisecond))
+
+	if len(unmatchedDeployed) > 0 {
+		globalDifferencesFound = true
+		if !hasTextReport || *quietPtr {
+			for _, resource := range unmatchedDeployed {
+				warnf("Deployed %s matches -selector but has no local file", resource)
+			}
+		}
+	}
+
+	if len(orphans) > 0 {
+		globalDifferencesFound = true
+		stats.Orphans = len(orphans)
+		if !hasTextReport || *quietPtr {
+			for _, description := range orphans {
+				warnf("Deployed %s has no local manifest (-detect-orphans)", description)
+			}
+		}
+	}
+
+	if *writePatchPtr != "" && len(patches) > 0 {
+		if err := writePatchFile(*writePatchPtr, patches, *forcePtr); err != nil {
+			fatalf("Failed to write patch: %v", err)
+		}
+		infof("Wrote patch for %d resources to %s\n", len(patches), *writePatchPtr)
+	}
+
+	if interrupted > 0 {
+		stats.Checked -= interrupted
+		warnf("Interrupted: %d of %d resources were not checked; the report is partial", interrupted, len(localResources))
+	}
+
+	for _, namespace := range targetNamespaces {
+		if drifted[namespace] {
+			stats.DriftedNamespaces = append(stats.DriftedNamespaces, namespace)
+		}
+	}
+	summary := RunSummary{
+		Stats:             stats,
+		DifferencesFound:  globalDifferencesFound,
+		UnmatchedDeployed: unmatchedDeployed,
+		Orphans:           orphans,
+		Interrupted:       interrupted,
+		Files:             len(files),
+		Duration:          time.Since(runStart),
+	}
+	for _, reporter := range reporters {
+		if err := reporter.Finish(summary); err != nil {
+			fatalf("Failed to write report: %v", err)
+		}
+	}
+
+	debugf("Run completed in %s", time.Since(runStart).Round(time.Millisecond))
+
+	// Set exit code based on whether any errors or differences were found
+	if runCtx.Err() != nil {
+		os.Exit(exitInterrupted) // Stopped by Ctrl-C or SIGTERM; the report may be partial
+	}
+	if errorsLogged.Load() > 0 {
+		os.Exit(exitError) // Some files or resources could not be checked
+	}
+	if globalDifferencesFound && !*exitZeroPtr {
+		os.Exit(exitDifferences) // Indicates failure due to differences
+	}
+	os.Exit(exitMatch) // Indicates success, or drift was found but -exit-zero is set
 }
 
-// DeployedData represents the structure of a deployed Kubernetes Secret or ConfigMap
-type DeployedData struct {
-	Type      string
-	Name      string
+// ParseOptions controls how local manifests are parsed
+type ParseOptions struct {
+	// Namespace, when set, replaces the namespace of every parsed resource
 	Namespace string
-	Data      map[string]string
+	// DefaultNamespace is used for resources without a namespace, like kubectl
+	// uses the namespace of the current context
+	DefaultNamespace string
+	// Format is the format of local files: yaml (default) or dotenv
+	Format string
+	// DotenvName is the name of the Secret a dotenv file is compared against
+	DotenvName string
+	// Sops decrypts files with sops before parsing them
+	Sops bool
+	// TemplateValues renders files ending in .tmpl with text/template when set
+	TemplateValues map[string]interface{}
+	// Strict skips Secrets and ConfigMaps whose apiVersion is not v1 instead of only
+	// warning, and those with base64 values the API server would reject
+	Strict bool
+}
+
+// readLocalFile reads a local file, rendering it first when it is a template
+func readLocalFile(filePath string, opts ParseOptions) ([]byte, error) {
+	data, err := readRawFile(filePath, opts)
+	if err != nil || !isTemplate(filePath) {
+		return data, err
+	}
+	if opts.TemplateValues == nil {
+		return nil, fmt.Errorf("file is a template; pass -template-values to render it")
+	}
+	return renderTemplate(filePath, data, opts.TemplateValues)
+}
+
+// readRawFile reads a local file or stdin, decrypting it first when opts.Sops is set
+func readRawFile(filePath string, opts ParseOptions) ([]byte, error) {
+	if opts.Sops {
+		return decryptSops(filePath)
+	}
+	if filePath == stdinPath {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return data, nil
+}
+
+// stdinPath is the file name that stands for standard input, as used by -stdin
+const stdinPath = "-"
+
+// parseLocalFile parses a local file according to opts.Format
+func parseLocalFile(filePath string, opts ParseOptions) ([]LocalResource, error) {
+	if opts.Format == "dotenv" {
+		return parseDotenvResource(filePath, opts)
+	}
+	return parseYAMLResources(filePath, opts)
+}
+
+// parseYAMLResources reads and parses a YAML file that may contain multiple documents,
+// returning a slice of LocalResource (either a KubernetesSecret or KubernetesConfig).
+func parseYAMLResources(filePath string, opts ParseOptions) ([]LocalResource, error) {
+	data, err := readLocalFile(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	var resources []LocalResource
+
+	// Documents are numbered from 1 in logs, e.g. "secrets.yaml[doc 3]"
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("error decoding YAML document %d: %w", doc, err)
+		}
+
+		location := fmt.Sprintf("%s[doc %d]", filepath.Base(filePath), doc)
+		resources = append(resources, decodeResources(&node, location, opts)...)
+	}
+
+	return resources, nil
+}
+
+// decodeResources decodes a single YAML document into local resources.
+// A "kind: List" document yields one resource per supported item. location
+// names the document in logs.
+func decodeResources(node *yaml.Node, location string, opts ParseOptions) []LocalResource {
+	// Documents holding only comments, e.g. from templates rendered empty, are skipped
+	if node.Kind == 0 || (node.Kind == yaml.DocumentNode && (len(node.Content) == 0 || node.Content[0].Tag == "!!null")) {
+		return nil
+	}
+	// Read the "kind" field to decide how to decode.
+	var meta struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	if err := node.Decode(&meta); err != nil {
+		warnf("Skipping document in file '%s': %v", location, err)
+		return nil
+	}
+
+	switch meta.Kind {
+	case "Secret":
+		dropNonScalarValues(node, meta.Kind, meta.Metadata.Name, location, "stringData", "data")
+		var secret KubernetesSecret
+		if err := node.Decode(&secret); err != nil {
+			errorf("Error decoding Secret in file '%s': %v", location, err)
+			return nil
+		}
+		// Validate required fields.
+		if secret.Metadata.Name == "" {
+			warnf("Skipping Secret with missing name  in file '%s'\n", location)
+			return nil
+		}
+		if opts.Namespace != "" {
+			secret.Metadata.Namespace = opts.Namespace
+		} else if secret.Metadata.Namespace == "" {
+			secret.Metadata.Namespace = opts.DefaultNamespace
+		}
+		// Validate required fields.
+		if secret.Metadata.Namespace == "" {
+			warnf("Skipping Secret with missing namespace in file '%s'\n", location)
+			return nil
+		}
+		if len(secret.StringData) == 0 && len(secret.Data) == 0 {
+			warnf("Skipping Secret '%s' in namespace '%s' with no 'stringData' or 'data' in file '%s'\n", secret.Metadata.Name, secret.Metadata.Namespace, location)
+			return nil
+		}
+		if !checkAPIVersion("Secret", secret.APIVersion, secret.Metadata.Name, secret.Metadata.Namespace, location, opts) {
+			return nil
+		}
+		if _, err := secret.DecodeData(); err != nil {
+			errorf("Error decoding Secret '%s' in namespace '%s' in file '%s': %v\n", secret.Metadata.Name, secret.Metadata.Namespace, location, err)
+			return nil
+		}
+		if !checkPadding("Secret", "data", secret.UnpaddedKeys(), secret.Metadata.Name, secret.Metadata.Namespace, location, opts) {
+			return nil
+		}
+		for _, key := range secret.OverlappingKeys() {
+			warnf("Key '%s' of Secret '%s' in namespace '%s' is set in both 'data' and 'stringData' in file '%s'; the 'stringData' value is compared, as the API server would store it", key, secret.Metadata.Name, secret.Metadata.Namespace, location)
+		}
+		return []LocalResource{&secret}
+	case "ConfigMap":
+		dropNonScalarValues(node, meta.Kind, meta.Metadata.Name, location, "data", "binaryData")
+		var config KubernetesConfig
+		if err := node.Decode(&config); err != nil {
+			errorf("Error decoding ConfigMap in file '%s': %v", location, err)
+			return nil
+		}
+		// Validate required fields.
+		if config.Metadata.Name == "" {
+			warnf("Skipping ConfigMap with missing name in file '%s'\n", location)
+			return nil
+		}
+		if opts.Namespace != "" {
+			config.Metadata.Namespace = opts.Namespace
+		} else if config.Metadata.Namespace == "" {
+			config.Metadata.Namespace = opts.DefaultNamespace
+		}
+		// Validate required fields.
+		if config.Metadata.Namespace == "" {
+			warnf("Skipping ConfigMap with missing namespace in file '%s'\n", location)
+			return nil
+		}
+		if len(config.Data) == 0 && len(config.BinaryData) == 0 {
+			warnf("Skipping ConfigMap '%s' in namespace '%s' with no 'data' or 'binaryData' in file '%s'\n", config.Metadata.Name, config.Metadata.Namespace, location)
+			return nil
+		}
+		if !checkAPIVersion("ConfigMap", config.APIVersion, config.Metadata.Name, config.Metadata.Namespace, location, opts) {
+			return nil
+		}
+		if _, err := config.DecodeBinaryData(); err != nil {
+			errorf("Error decoding ConfigMap '%s' in namespace '%s' in file '%s': %v\n", config.Metadata.Name, config.Metadata.Namespace, location, err)
+			return nil
+		}
+		if !checkPadding("ConfigMap", "binaryData", config.UnpaddedKeys(), config.Metadata.Name, config.Metadata.Namespace, location, opts) {
+			return nil
+		}
+		return []LocalResource{&config}
+	case "List":
+		var list struct {
+			Items []yaml.Node `yaml:"items"`
+		}
+		if err := node.Decode(&list); err != nil {
+			errorf("Error decoding List in file '%s': %v", location, err)
+			return nil
+		}
+		var resources []LocalResource
+		for i := range list.Items {
+			resources = append(resources, decodeResources(&list.Items[i], fmt.Sprintf("%s, item %d]", strings.TrimSuffix(location, "]"), i+1), opts)...)
+		}
+		return resources
+	default:
+		// Rendered charts contain many unrelated kinds, so this is only logged at debug level
+		if source := helmSource(node); source != "" {
+			debugf("Skipping unsupported kind: %s in file '%s' (source: %s)", meta.Kind, location, source)
+		} else {
+			debugf("Skipping unsupported kind: %s in file '%s'", meta.Kind, location)
+		}
+		return nil
+	}
+}
+
+// dropNonScalarValues removes the keys under the given data fields whose value is a
+// mapping or list rather than a string, reporting each by key, line and file, so the
+// remaining keys of the resource can still be compared
+func dropNonScalarValues(node *yaml.Node, kind, name, location string, fields ...string) {
+	root := node
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		field, values := root.Content[i].Value, root.Content[i+1]
+		if !contains(fields, field) || values.Kind != yaml.MappingNode {
+			continue
+		}
+		kept := values.Content[:0]
+		for j := 0; j+1 < len(values.Content); j += 2 {
+			key, value := values.Content[j], values.Content[j+1]
+			resolved := value
+			if resolved.Kind == yaml.AliasNode && resolved.Alias != nil {
+				resolved = resolved.Alias
+			}
+			if resolved.Kind == yaml.MappingNode || resolved.Kind == yaml.SequenceNode {
+				shape := "mapping"
+				if resolved.Kind == yaml.SequenceNode {
+					shape = "list"
+				}
+				errorf("Skipping key '%s' in %s of %s '%s' in file '%s' (line %d): the value is a %s, not a string; use a '|' block to store structured content", key.Value, field, kind, name, location, value.Line, shape)
+				continue
+			}
+			kept = append(kept, key, value)
+		}
+		values.Content = kept
+	}
+}
+
+// checkAPIVersion reports an apiVersion other than v1 on a Secret or ConfigMap.
+// It returns false when the resource should be skipped, which only happens with opts.Strict.
+func checkAPIVersion(kind, apiVersion, name, namespace, location string, opts ParseOptions) bool {
+	if apiVersion == "v1" {
+		return true
+	}
+	if opts.Strict {
+		errorf("Skipping %s '%s' in namespace '%s' in file '%s': apiVersion is '%s', expected 'v1'", kind, name, namespace, location, apiVersion)
+		return false
+	}
+	warnf("%s '%s' in namespace '%s' in file '%s' has apiVersion '%s', expected 'v1'", kind, name, namespace, location, apiVersion)
+	return true
 }
 
-// SecretDifference represents a difference in a key-value pair
-type SecretDifference struct {
-	Key      string
-	Local    *string
-	Deployed *string
+// checkPadding reports keys whose base64 lacks padding, which are compared but
+// rejected by the API server. With opts.Strict (and -lint) each is an error and
+// the resource is skipped.
+func checkPadding(kind, field string, keys []string, name, namespace, location string, opts ParseOptions) bool {
+	if !opts.Strict {
+		return true
+	}
+	for _, key := range keys {
+		errorf("Key '%s' in %s of %s '%s' in namespace '%s' in file '%s' is base64 without padding, which the API server rejects", key, field, kind, name, namespace, location)
+	}
+	return len(keys) == 0
 }
 
-// LocalResource is an interface to unify local Secrets and ConfigMaps.
-type LocalResource interface {
-	GetName() string
-	GetNamespace() string
-	GetKind() string
-	GetLocalData() map[string]string
-	GetMergeField() string // "stringData" for Secrets; "data" for ConfigMaps.
+// helmSource returns the template path from a "# Source:" comment that
+// `helm template` writes above each document, or "" if there is none
+func helmSource(node *yaml.Node) string {
+	// Depending on the blank lines around it, the comment is attached to the
+	// document, the mapping or its first key
+	var comments []string
+	for n := node; n != nil; {
+		comments = append(comments, n.HeadComment)
+		if len(n.Content) == 0 || n.Kind == yaml.ScalarNode {
+			break
+		}
+		n = n.Content[0]
+	}
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			if source := strings.TrimPrefix(strings.TrimSpace(line), "# Source:"); source != strings.TrimSpace(line) {
+				return strings.TrimSpace(source)
+			}
+		}
+	}
+	return ""
 }
 
-// Implement LocalResource for KubernetesSecret.
-func (s *KubernetesSecret) GetName() string                 { return s.Metadata.Name }
-func (s *KubernetesSecret) GetNamespace() string            { return s.Metadata.Namespace }
-func (s *KubernetesSecret) GetKind() string                 { return s.Kind }
-func (s *KubernetesSecret) GetLocalData() map[string]string { return s.StringData }
-func (s *KubernetesSecret) GetMergeField() string           { return "stringData" }
+// parsePatterns processes the provided pattern string and returns a slice of glob patterns
+func parsePatterns(patternStr, dir string) []string {
+	var patterns []string
+	rawPatterns := strings.Split(patternStr, ",")
+	for _, p := range rawPatterns {
+		trimmed := strings.TrimSpace(p)
+		if trimmed != "" {
+			fullPattern := filepath.Join(dir, trimmed)
+			patterns = append(patterns, fullPattern)
+		}
+	}
+	return patterns
+}
 
-// Implement LocalResource for KubernetesConfig.
-func (c *KubernetesConfig) GetName() string                 { return c.Metadata.Name }
-func (c *KubernetesConfig) GetNamespace() string            { return c.Metadata.Namespace }
-func (c *KubernetesConfig) GetKind() string                 { return c.Kind }
-func (c *KubernetesConfig) GetLocalData() map[string]string { return c.Data }
-func (c *KubernetesConfig) GetMergeField() string           { return "data" }
+// ClientOptions controls how the Kubernetes client is configured
+type ClientOptions struct {
+	Kubeconfig     string   // explicit kubeconfig path, overrides KubeconfigData and KUBECONFIG
+	KubeconfigData string   // raw kubeconfig YAML, used instead of KUBECONFIG and ~/.kube/config
+	Context        string   // kubeconfig context, defaults to the current context
+	InCluster      bool     // require the in-cluster ServiceAccount config
+	As             string   // user or service account to impersonate
+	AsGroups       []string // groups to impersonate
+	Token          string   // bearer token replacing the kubeconfig credentials
+	QPS            float32  // client-side request rate limit
+	Burst          int      // requests allowed above QPS in a burst
+}
 
-func main() {
-	// Define command-line flags
-	dirPtr := flag.String("dir", ".", "Directory to scan for config and secret YAML files")
-	patternPtr := flag.String("pattern", "*secret*.yaml,*secret*.yml,*config*.yaml,*config*.yml", "Comma-separated glob patterns to identify secret & config YAML files (e.g., \"*secret*.yaml,*secret*.yml\")")
-	verbosePtr := flag.Bool("verbose", false, "Enable verbose logging")
-	flag.Parse()
+// findFiles returns the files in dir matching the comma-separated patterns,
+// de-duplicated and sorted. Patterns containing a slash are matched against the
+// path relative to dir, where "**" matches any number of directories. With
+// recursive set, subdirectories are walked and patterns without a slash are
+// matched against each file's base name.
+func findFiles(dir, patternStr string, recursive bool) ([]string, error) {
+	seen := make(map[string]struct{})
+	var files []string
+	addFile := func(path string) {
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			files = append(files, path)
+		}
+	}
 
-	// Set up logging
-	if *verbosePtr {
-		log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	} else {
-		log.SetFlags(0)
+	// Patterns are matched relative to dir, so don't join them with it
+	patterns := parsePatterns(patternStr, "")
+	doubleStar := false
+	for i, pattern := range patterns {
+		patterns[i] = filepath.ToSlash(pattern)
+		if err := validatePathPattern(patterns[i]); err != nil {
+			return nil, fmt.Errorf("error processing pattern '%s': %w", pattern, err)
+		}
+		doubleStar = doubleStar || strings.Contains(patterns[i], "**")
 	}
-	log.SetOutput(os.Stdout)
 
-	// Create Kubernetes client
-	clientset, err := getKubernetesClient()
-	if err != nil {
-		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	if !recursive && !doubleStar {
+		for _, pattern := range parsePatterns(patternStr, dir) {
+			matchedFiles, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("error processing pattern '%s': %w", pattern, err)
+			}
+			for _, file := range matchedFiles {
+				addFile(file)
+			}
+		}
+		sort.Strings(files)
+		return files, nil
 	}
 
-	// Process file patterns
-	patterns := parsePatterns(*patternPtr, *dirPtr)
-	var files []string
-	for _, pattern := range patterns {
-		matchedFiles, err := filepath.Glob(pattern)
+	// A single walk serves both -recursive and "**" patterns
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
 		if err != nil {
-			log.Fatalf("Error processing pattern '%s': %v", pattern, err)
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range patterns {
+			name := rel
+			if recursive && !strings.Contains(pattern, "/") {
+				name = d.Name()
+			}
+			if matchPathPattern(pattern, name) {
+				addFile(path)
+				break
+			}
 		}
-		files = append(files, matchedFiles...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	sort.Strings(files)
+	return files, nil
+}
 
-	if len(files) == 0 {
-		log.Println("No YAML files matching the specified patterns were found in the directory.")
-		return
+// findFilesInDirs runs findFiles for every directory and merges the results.
+// A file reached through several directories, e.g. overlapping roots with
+// -recursive, is listed once under the first path it was found by.
+func findFilesInDirs(dirs []string, patternStr string, recursive bool) ([]string, error) {
+	if len(dirs) == 0 {
+		dirs = []string{"."}
 	}
-
-	// Variable to track if any differences were found across all files
-	var globalDifferencesFound bool = false
-
-	for _, file := range files {
-		log.Printf("Processing file: %s\n", filepath.Base(file))
-		localResources, err := parseYAMLResources(file)
+	seen := make(map[string]bool)
+	var files []string
+	for _, dir := range dirs {
+		dirFiles, err := findFiles(dir, patternStr, recursive)
 		if err != nil {
-			log.Printf("Error parsing YAML file '%s': %v\n", filepath.Base(file), err)
-			continue
+			return nil, fmt.Errorf("error scanning directory '%s': %w", dir, err)
 		}
-
-		// Process each local resource
-		for _, resource := range localResources {
-			var deployed *DeployedData
-			switch resource.GetKind() {
-			case "Secret":
-				deployed, err = getDeployedSecret(clientset, resource.GetNamespace(), resource.GetName())
-			case "ConfigMap":
-				deployed, err = getDeployedConfig(clientset, resource.GetNamespace(), resource.GetName())
-			default:
-				log.Printf("Skipping unsupported resource type: %s\n", resource.GetKind())
-				continue
-			}
+		for _, file := range dirFiles {
+			abs, err := filepath.Abs(file)
 			if err != nil {
-				log.Printf("Error retrieving deployed %s '%s' in namespace '%s': %v\n", resource.GetKind(), resource.GetName(), resource.GetNamespace(), err)
-				continue
+				return nil, fmt.Errorf("error resolving path '%s': %w", file, err)
 			}
-			if deployed == nil {
-				log.Printf("Deployed %s '%s' in namespace '%s' not found.\n", resource.GetKind(), resource.GetName(), resource.GetNamespace())
-				continue
+			if !seen[abs] {
+				seen[abs] = true
+				files = append(files, file)
 			}
-
-			// Use unified comparison logic.
-			differences := compareData(resource.GetLocalData(), deployed.Data)
-			printDifferences(resource.GetKind(), resource.GetName(), resource.GetNamespace(), differences, resource.GetMergeField(), &globalDifferencesFound)
 		}
 	}
-
-	// Set exit code based on whether any differences were found
-	if globalDifferencesFound {
-		fmt.Println("Summary: Differences were found in some resources.")
-		os.Exit(1) // Indicates failure due to differences
-	} else {
-		fmt.Println("Summary: All secrets match across environments.")
-		os.Exit(0) // Indicates success
+	if len(dirs) > 1 {
+		sort.Strings(files)
 	}
+	return files, nil
 }
 
-// parseYAMLResources reads and parses a YAML file that may contain multiple documents,
-// returning a slice of LocalResource (either a KubernetesSecret or KubernetesConfig).
-func parseYAMLResources(filePath string) ([]LocalResource, error) {
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+// validatePathPattern checks that every segment of a slash-separated pattern is a valid glob
+func validatePathPattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
-	var resources []LocalResource
+// matchPathPattern reports whether a slash-separated path matches pattern.
+// Each segment is matched with path.Match, and a "**" segment matches zero or
+// more directories.
+func matchPathPattern(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
 
-	for {
-		var node yaml.Node
-		err := decoder.Decode(&node)
-		if err != nil {
-			if err == io.EOF {
-				break
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
 			}
-			return nil, fmt.Errorf("error decoding YAML: %w", err)
+			return false
 		}
-
-		// Read the "kind" field to decide how to decode.
-		var meta struct {
-			Kind string `yaml:"kind"`
+		if len(name) == 0 {
+			return false
 		}
-		if err := node.Decode(&meta); err != nil {
-			log.Printf("Skipping document in file '%s': %v", filepath.Base(filePath), err)
-			continue
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
 		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
 
-		switch meta.Kind {
-		case "Secret":
-			var secret KubernetesSecret
-			if err := node.Decode(&secret); err != nil {
-				log.Printf("Error decoding Secret in file '%s': %v", filepath.Base(filePath), err)
-				continue
-			}
-			// Validate required fields.
-			if secret.Metadata.Name == "" {
-				log.Printf("Skipping Secret with missing name  in file '%s'\n", filepath.Base(filePath))
-				continue
-			}
-			// Validate required fields.
-			if secret.Metadata.Namespace == "" {
-				log.Printf("Skipping Secret with missing namespace in file '%s'\n", filepath.Base(filePath))
-				continue
-			}
-			if len(secret.StringData) == 0 {
-				log.Printf("Skipping Secret '%s' in namespace '%s' with no 'stringData' in file '%s'\n", secret.Metadata.Name, secret.Metadata.Namespace, filepath.Base(filePath))
-				continue
-			}
-			resources = append(resources, &secret)
-		case "ConfigMap":
-			var config KubernetesConfig
-			if err := node.Decode(&config); err != nil {
-				log.Printf("Error decoding ConfigMap in file '%s': %v", filepath.Base(filePath), err)
-				continue
-			}
-			// Validate required fields.
-			if config.Metadata.Name == "" {
-				log.Printf("Skipping ConfigMap with missing name in file '%s'\n", filepath.Base(filePath))
-				continue
-			}
-			// Validate required fields.
-			if config.Metadata.Namespace == "" {
-				log.Printf("Skipping ConfigMap with missing namespace in file '%s'\n", filepath.Base(filePath))
-				continue
-			}
-			if len(config.Data) == 0 {
-				log.Printf("Skipping ConfigMap '%s' in namespace '%s' with no 'data' in file '%s'\n", config.Metadata.Name, config.Metadata.Namespace, filepath.Base(filePath))
-				continue
-			}
-			resources = append(resources, &config)
-		default:
-			log.Printf("Skipping unsupported kind: %s in file '%s'\n", meta.Kind, filepath.Base(filePath))
-			continue
+// reportDuplicates warns about every resource defined more than once and
+// returns the number of such resources
+func reportDuplicates(definedIn map[string][]string) int {
+	var keys []string
+	for key, files := range definedIn {
+		if len(files) > 1 {
+			keys = append(keys, key)
 		}
 	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		warnf("%s '%s' in namespace '%s' is defined %d times, in: %s", parts[0], parts[2], parts[1], len(definedIn[key]), strings.Join(definedIn[key], ", "))
+	}
+	return len(keys)
+}
 
-	return resources, nil
+// ResourceFilter selects resources by name, namespace and kind.
+// Each field holds names or glob patterns; an empty field matches everything.
+type ResourceFilter struct {
+	Names      []string
+	Namespaces []string
+	Kinds      []string
 }
 
-// parsePatterns processes the provided pattern string and returns a slice of glob patterns
-func parsePatterns(patternStr, dir string) []string {
-	var patterns []string
-	rawPatterns := strings.Split(patternStr, ",")
-	for _, p := range rawPatterns {
-		trimmed := strings.TrimSpace(p)
-		if trimmed != "" {
-			fullPattern := filepath.Join(dir, trimmed)
-			patterns = append(patterns, fullPattern)
+// filterResources returns the resources matching all of the filter's fields
+func filterResources(resources []LocalResource, filter ResourceFilter) []LocalResource {
+	matches := func(value string, patterns []string) bool {
+		return len(patterns) == 0 || compare.MatchesAnyPattern(value, patterns)
+	}
+
+	var filtered []LocalResource
+	for _, resource := range resources {
+		if matches(resource.GetName(), filter.Names) &&
+			matches(resource.GetNamespace(), filter.Namespaces) &&
+			matches(resource.GetKind(), filter.Kinds) {
+			filtered = append(filtered, resource)
 		}
 	}
-	return patterns
+	return filtered
 }
 
-// getKubernetesClient initializes and returns a Kubernetes clientset
-func getKubernetesClient() (*kubernetes.Clientset, error) {
-	// Use the current context in kubeconfig
-	kubeconfigPath := filepath.Join(homeDir(), ".kube", "config")
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+// getKubernetesClient initializes and returns a Kubernetes clientset.
+// The kubeconfig is resolved as -kubeconfig flag > KUBECONFIG > ~/.kube/config.
+// When running inside a pod and no kubeconfig or context is requested, the
+// mounted ServiceAccount token is used instead.
+func getKubernetesClient(opts ClientOptions) (*kubernetes.Clientset, string, error) {
+	config, namespace, err := buildRESTConfig(opts)
 	if err != nil {
-		return nil, fmt.Errorf("error building kubeconfig: %w", err)
+		return nil, "", err
 	}
+	applyAuthOverrides(config, opts)
+	applyRateLimits(config, opts.QPS, opts.Burst)
 
 	// Create the clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes client: %w", err)
+		return nil, "", fmt.Errorf("error creating Kubernetes client: %w", err)
+	}
+
+	return clientset, namespace, nil
+}
+
+// applyAuthOverrides applies the bearer token and impersonation settings to config
+func applyAuthOverrides(config *rest.Config, opts ClientOptions) {
+	if opts.Token != "" {
+		if config.BearerToken != "" || config.BearerTokenFile != "" || config.Username != "" ||
+			config.CertFile != "" || len(config.CertData) > 0 || config.AuthProvider != nil || config.ExecProvider != nil {
+			warnf("-token replaces the credentials configured in the kubeconfig")
+		}
+		// Drop the other credentials so the token is the only identity presented
+		config.BearerToken = opts.Token
+		config.BearerTokenFile = ""
+		config.Username, config.Password = "", ""
+		config.CertFile, config.KeyFile = "", ""
+		config.CertData, config.KeyData = nil, nil
+		config.AuthProvider = nil
+		config.ExecProvider = nil
+	}
+	if opts.As != "" || len(opts.AsGroups) > 0 {
+		config.Impersonate = rest.ImpersonationConfig{UserName: opts.As, Groups: opts.AsGroups}
+	}
+}
+
+// buildRESTConfig resolves the rest.Config from in-cluster or kubeconfig sources,
+// along with the default namespace of the context or ServiceAccount
+func buildRESTConfig(opts ClientOptions) (*rest.Config, string, error) {
+	if opts.InCluster {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, "", fmt.Errorf("error loading in-cluster config: %w", err)
+		}
+		infof("Using in-cluster ServiceAccount config (-in-cluster)")
+		return config, inClusterNamespace(), nil
+	}
+	// An explicit KUBECONFIG wins over the pod's ServiceAccount, e.g. in CI runners running in pods
+	kubeconfigEnv := os.Getenv(clientcmd.RecommendedConfigPathEnvVar)
+	if opts.Kubeconfig == "" && opts.KubeconfigData == "" && opts.Context == "" && kubeconfigEnv == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			infof("Using in-cluster ServiceAccount config")
+			return config, inClusterNamespace(), nil
+		}
+	}
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.Context}
+	var clientConfig clientcmd.ClientConfig
+	if opts.Kubeconfig == "" && opts.KubeconfigData != "" {
+		// Kept in memory so CI secrets never have to be written to disk
+		rawConfig, err := clientcmd.Load([]byte(opts.KubeconfigData))
+		if err != nil {
+			return nil, "", fmt.Errorf("error parsing kubeconfig data: %w", err)
+		}
+		clientConfig = clientcmd.NewNonInteractiveClientConfig(*rawConfig, opts.Context, overrides, nil)
+		infof("Using kubeconfig data (-kubeconfig-data or %s)", kubeconfigDataEnv)
+	} else {
+		if opts.KubeconfigData != "" {
+			debugf("-kubeconfig takes precedence over the kubeconfig data")
+		}
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		switch {
+		case opts.Kubeconfig != "":
+			loadingRules.ExplicitPath = opts.Kubeconfig
+			infof("Using kubeconfig '%s' (-kubeconfig)", opts.Kubeconfig)
+		case kubeconfigEnv != "":
+			infof("Using kubeconfig '%s' (%s)", kubeconfigEnv, clientcmd.RecommendedConfigPathEnvVar)
+		default:
+			infof("Using kubeconfig '%s'", clientcmd.RecommendedHomeFile)
+		}
+		clientConfig = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	}
+
+	if opts.Context != "" {
+		rawConfig, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, "", fmt.Errorf("error loading kubeconfig: %w", err)
+		}
+		if _, ok := rawConfig.Contexts[opts.Context]; !ok {
+			return nil, "", fmt.Errorf("context '%s' not found in kubeconfig", opts.Context)
+		}
+	}
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("error building kubeconfig: %w", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading the context namespace: %w", err)
 	}
+	return config, namespace, nil
+}
+
+// kubeconfigDataEnv is the environment variable read as the default of -kubeconfig-data
+const kubeconfigDataEnv = "SECRET_COMPARE_KUBECONFIG_DATA"
+
+// inClusterNamespaceFile holds the namespace of the pod's ServiceAccount
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 
-	return clientset, nil
+// inClusterNamespace returns the namespace the pod runs in, or "" if unknown
+func inClusterNamespace() string {
+	data, err := ioutil.ReadFile(inClusterNamespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
 }
 
 // getDeployedSecret retrieves a deployed Kubernetes Secret from the cluster
-func getDeployedSecret(clientset *kubernetes.Clientset, namespace, name string) (*DeployedData, error) {
-	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+func getDeployedSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*DeployedData, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Secret does not exist in the deployed cluster
 			return nil, nil
 		}
-		return nil, fmt.Errorf("error fetching secret: %w", err)
+		return nil, describeAPIError(err, "secrets", namespace)
 	}
 
+	return secretData(secret), nil
+}
+
+// secretData converts a Secret fetched from the API into DeployedData
+func secretData(secret *corev1.Secret) *DeployedData {
 	// Since client-go decodes 'data', we can directly use it
 	decodedData := make(map[string]string)
 	for key, value := range secret.Data {
@@ -304,151 +1612,407 @@ func getDeployedSecret(clientset *kubernetes.Clientset, namespace, name string)
 	}
 
 	return &DeployedData{
-		Type:      "secret",
-		Name:      secret.Name,
-		Namespace: secret.Namespace,
-		Data:      decodedData,
-	}, nil
+		Type:            "secret",
+		Name:            secret.Name,
+		Namespace:       secret.Namespace,
+		Data:            decodedData,
+		SecretType:      string(secret.Type),
+		Immutable:       secret.Immutable,
+		Controller:      controllerOf(secret.OwnerReferences),
+		ResourceVersion: secret.ResourceVersion,
+		Finalizers:      secret.Finalizers,
+		Labels:          secret.Labels,
+		Annotations:     secret.Annotations,
+	}
 }
 
 // getDeployedConfig retrieves a deployed Kubernetes ConfigMap from the cluster
-func getDeployedConfig(clientset *kubernetes.Clientset, namespace, name string) (*DeployedData, error) {
-	config, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+func getDeployedConfig(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*DeployedData, error) {
+	config, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Secret does not exist in the deployed cluster
 			return nil, nil
 		}
-		return nil, fmt.Errorf("error fetching configmap: %w", err)
+		return nil, describeAPIError(err, "configmaps", namespace)
 	}
 
-	return &DeployedData{
-		Type:      "configmap",
-		Name:      config.Name,
-		Namespace: config.Namespace,
-		Data:      config.Data,
-	}, nil
+	return configMapData(config), nil
 }
 
-// compareData compares the local data with the deployed data and returns differences
-func compareData(local, deployed map[string]string) []SecretDifference {
-	var differences []SecretDifference
+// configMapData converts a ConfigMap fetched from the API into DeployedData
+func configMapData(config *corev1.ConfigMap) *DeployedData {
+	data := make(map[string]string, len(config.Data)+len(config.BinaryData))
+	for key, value := range config.Data {
+		data[key] = value
+	}
+	binaryKeys := make(map[string]bool, len(config.BinaryData))
+	for key, value := range config.BinaryData {
+		data[key] = string(value)
+		binaryKeys[key] = true
+	}
 
-	// Create a set of all keys
-	keysSet := make(map[string]struct{})
-	for key := range local {
-		keysSet[key] = struct{}{}
+	return &DeployedData{
+		Type:            "configmap",
+		Name:            config.Name,
+		Namespace:       config.Namespace,
+		Data:            data,
+		BinaryKeys:      binaryKeys,
+		Immutable:       config.Immutable,
+		Controller:      controllerOf(config.OwnerReferences),
+		ResourceVersion: config.ResourceVersion,
+		Finalizers:      config.Finalizers,
+		Labels:          config.Labels,
+		Annotations:     config.Annotations,
 	}
-	for key := range deployed {
-		keysSet[key] = struct{}{}
+}
+
+// listPageSize is the number of resources requested per page when listing
+const listPageSize = 250
+
+// listDeployedSecrets lists the Secrets in namespace matching the selectors in opts,
+// following continue tokens so large namespaces are fetched page by page
+func listDeployedSecrets(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) ([]*DeployedData, error) {
+	var deployed []*DeployedData
+	opts.Limit = listPageSize
+	for {
+		list, err := clientset.CoreV1().Secrets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, describeAPIError(err, "secrets", namespace)
+		}
+		for i := range list.Items {
+			deployed = append(deployed, secretData(&list.Items[i]))
+		}
+		if list.Continue == "" {
+			return deployed, nil
+		}
+		opts.Continue = list.Continue
 	}
+}
 
-	for key := range keysSet {
-		localVal, localExists := local[key]
-		deployedVal, deployedExists := deployed[key]
+// listDeployedConfigs lists the ConfigMaps in namespace matching the selectors in opts,
+// following continue tokens so large namespaces are fetched page by page
+func listDeployedConfigs(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) ([]*DeployedData, error) {
+	var deployed []*DeployedData
+	opts.Limit = listPageSize
+	for {
+		list, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, describeAPIError(err, "configmaps", namespace)
+		}
+		for i := range list.Items {
+			deployed = append(deployed, configMapData(&list.Items[i]))
+		}
+		if list.Continue == "" {
+			return deployed, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
 
-		if !localExists && deployedExists {
-			diff := SecretDifference{
-				Key:      key,
-				Local:    nil,
-				Deployed: &deployedVal,
-			}
-			differences = append(differences, diff)
-		} else if localExists && !deployedExists {
-			diff := SecretDifference{
-				Key:      key,
-				Local:    &localVal,
-				Deployed: nil,
-			}
-			differences = append(differences, diff)
-		} else if localExists && deployedExists && localVal != deployedVal {
-			diff := SecretDifference{
-				Key:      key,
-				Local:    &localVal,
-				Deployed: &deployedVal,
+// parseEquivalenceSets parses "a=b=c,d=e" into a lookup of value to class id
+func parseEquivalenceSets(setStr string) map[string]int {
+	equivalences := make(map[string]int)
+	for i, set := range strings.Split(setStr, ",") {
+		for _, value := range strings.Split(set, "=") {
+			trimmed := strings.TrimSpace(value)
+			if trimmed != "" {
+				equivalences[trimmed] = i
 			}
-			differences = append(differences, diff)
 		}
 	}
+	return equivalences
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag
+type stringList []string
 
-	return differences
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+// Set appends a value each time the flag is given
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
-// printDifferences prints the comparison results and outputs YAML snippets
-// for key-value pairs that should be merged locally.
-func printDifferences(kind, name, namespace string, differences []SecretDifference, mergeField string, globalDiffFound *bool) {
-	if len(differences) == 0 {
-		fmt.Printf("=== %s (Namespace: %s) ===\nAll %s match between the local file and the deployed Kubernetes %s.\n\n", name, namespace, kind, kind)
-	} else {
-		*globalDiffFound = true
-		fmt.Printf("=== %s (Namespace: %s) ===\nDifferences found:\n", name, namespace)
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
 
-		missingLocalKeys := make(map[string]string)
-		replaceLocalKeys := make(map[string]string)
+// ignoreKeysAnnotation lists keys, or glob patterns, a manifest excludes from its own comparison
+const ignoreKeysAnnotation = "secret-compare/ignore-keys"
 
-		for _, diff := range differences {
-			switch {
-			case diff.Local != nil && diff.Deployed != nil:
-				fmt.Printf(" - [DIFFERENT] %s:\n", diff.Key)
-				fmt.Printf("   Local:     %s\n", *diff.Local)
-				fmt.Printf("   Deployed:  %s\n\n", *diff.Deployed)
-				replaceLocalKeys[diff.Key] = *diff.Deployed
-			case diff.Local != nil && diff.Deployed == nil:
-				fmt.Printf(" - [ONLY IN LOCAL] %s:\n", diff.Key)
-				fmt.Printf("   Value: %s\n\n", *diff.Local)
-			case diff.Local == nil && diff.Deployed != nil:
-				fmt.Printf(" - [ONLY IN DEPLOYED] %s:\n", diff.Key)
-				fmt.Printf("   Value: %s\n\n", *diff.Deployed)
-				missingLocalKeys[diff.Key] = *diff.Deployed
-			}
+// optionsForResource returns the options for comparing a single resource: the keys in its
+// ignoreKeysAnnotation are ignored in addition to the global IgnoreKeys
+func optionsForResource(o CompareOptions, resource LocalResource) CompareOptions {
+	keys := splitList(resource.GetAnnotations()[ignoreKeysAnnotation])
+	if len(keys) == 0 {
+		return o
+	}
+	debugf("Ignoring keys %s for %s '%s' in namespace '%s' from its %s annotation", strings.Join(keys, ","), resource.GetKind(), resource.GetName(), resource.GetNamespace(), ignoreKeysAnnotation)
+	o.IgnoreKeys = append(keys, o.IgnoreKeys...)
+	return o
+}
+
+// matchingKeys returns the compared keys present on both sides that have no difference, sorted
+func matchingKeys(local, deployed map[string]string, differences []SecretDifference, opts CompareOptions) []string {
+	differing := make(map[string]bool, len(differences))
+	for _, diff := range differences {
+		differing[diff.Key] = true
+	}
+	var keys []string
+	for key := range local {
+		if _, ok := deployed[key]; ok && !differing[key] && opts.Includes(key) {
+			keys = append(keys, key)
 		}
+	}
+	sort.Strings(keys)
+	return keys
+}
 
-		// the new locals doenst need a copy snippet as is can de applied as it is
-		if len(replaceLocalKeys) > 0 {
-			fmt.Printf("Merge the following key-value pairs into your local file to match deployed %s:\n", strings.ToLower(kind))
-			fmt.Println("```yaml")
-			fmt.Printf("%s:\n", mergeField)
-			for key, value := range replaceLocalKeys {
-				fmt.Printf("  %s: %s\n", key, formatYAMLValue(value))
-			}
-			fmt.Println("```")
-			fmt.Println()
+// warnNonCanonicalEncoding notes Secret data keys that match the deployed value
+// once decoded but are not encoded the way the API server returns them. The
+// resource is not marked as drifted.
+func warnNonCanonicalEncoding(secret *KubernetesSecret, deployed *DeployedData, differences []SecretDifference, opts CompareOptions) {
+	differing := make(map[string]bool, len(differences))
+	for _, diff := range differences {
+		differing[diff.Key] = true
+	}
+	for _, key := range secret.NonCanonicalKeys() {
+		if _, ok := deployed.Data[key]; !ok || differing[key] || !opts.Includes(key) {
+			continue
 		}
-		if len(missingLocalKeys) > 0 {
-			fmt.Printf("Add the following key-value pairs locally to match the deployed %s:\n", strings.ToLower(kind))
-			fmt.Println("```yaml")
-			fmt.Printf("%s:\n", mergeField)
-			for key, value := range missingLocalKeys {
-				fmt.Printf("  %s: %s\n", key, formatYAMLValue(value))
-			}
-			fmt.Println("```")
-			fmt.Println()
+		warnf("Key '%s' of Secret '%s' in namespace '%s' matches the deployed value, but its base64 in data differs from the canonical encoding (padding or line wrapping)", key, secret.GetName(), secret.GetNamespace())
+	}
+}
+
+// describeAPIError turns common API failures into readable messages
+func describeAPIError(err error, resource, namespace string) error {
+	switch {
+	case errors.IsForbidden(err) && namespace == metav1.NamespaceAll:
+		return fmt.Errorf("permission denied: the current identity cannot list %s across all namespaces; check its RBAC ClusterRole/ClusterRoleBinding", resource)
+	case errors.IsForbidden(err):
+		return fmt.Errorf("permission denied: the current identity cannot get %s in namespace '%s'; check its RBAC Role/RoleBinding", resource, namespace)
+	case errors.IsUnauthorized(err):
+		return fmt.Errorf("unauthorized: the cluster rejected the credentials while fetching %s", resource)
+	default:
+		return fmt.Errorf("error fetching %s: %w", strings.TrimSuffix(resource, "s"), err)
+	}
+}
+
+// ComparisonResult holds everything found when comparing one resource
+type ComparisonResult struct {
+	Kind       string
+	Name       string
+	Namespace  string
+	MergeField string
+	Mask       bool
+	// NotDeployed marks a local resource missing from the cluster, reported with -fail-on-missing
+	NotDeployed bool
+	// Managed marks a deployed resource that looks operator-managed
+	Managed bool
+	// DumpDir is where -dump-dir wrote the differing values, if anywhere
+	DumpDir string
+	// ResourceVersion of the deployed resource the result was computed from
+	ResourceVersion string
+
+	Differences              []SecretDifference
+	MatchingKeys             int               // number of compared keys with equal values
+	Matching                 map[string]string // values of matching keys, only collected for verbose output
+	FinalizersOnlyInLocal    []string
+	FinalizersOnlyInDeployed []string
+	FieldDifferences         []FieldDifference
+	LabelDifferences         []SecretDifference
+	AnnotationDifferences    []SecretDifference
+}
+
+// RunStats counts the outcomes of a run for the summary
+type RunStats struct {
+	Checked         int // resources looked up
+	Matching        int
+	WithDifferences int
+	NotFound        int // resources missing from the cluster
+	Errors          int // lookups that failed
+	Orphans         int // deployed resources without a local manifest, with -detect-orphans
+	ManagedSkipped  int // operator-managed resources whose differences were ignored (-skip-managed)
+
+	Namespaces        []string // namespaces given with -namespaces
+	DriftedNamespaces []string // those of Namespaces with differences, in the same order
+
+	OnlyInLocal    int // differing keys by category
+	OnlyInDeployed int
+	Different      int
+}
+
+// Add counts a compared resource and its differing keys
+func (s *RunStats) Add(result ComparisonResult) {
+	if result.HasDifferences() {
+		s.WithDifferences++
+	} else {
+		s.Matching++
+	}
+	for _, diff := range result.Differences {
+		switch diffStatus(diff) {
+		case "ONLY_IN_LOCAL":
+			s.OnlyInLocal++
+		case "ONLY_IN_DEPLOYED":
+			s.OnlyInDeployed++
+		default:
+			s.Different++
+		}
+	}
+}
+
+// Print prints the counts below the summary line
+func (s RunStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "  Resources checked:    %d\n", s.Checked)
+	fmt.Fprintf(w, "  Fully matching:       %d\n", s.Matching)
+	fmt.Fprintf(w, "  With differences:     %d\n", s.WithDifferences)
+	fmt.Fprintf(w, "  Not found in cluster: %d\n", s.NotFound)
+	if s.Orphans > 0 {
+		fmt.Fprintf(w, "  Orphaned in cluster:  %d\n", s.Orphans)
+	}
+	if s.ManagedSkipped > 0 {
+		fmt.Fprintf(w, "  Managed, ignored:     %d (-skip-managed)\n", s.ManagedSkipped)
+	}
+	if len(s.Namespaces) > 0 {
+		drifted := "none"
+		if len(s.DriftedNamespaces) > 0 {
+			drifted = strings.Join(s.DriftedNamespaces, ", ")
 		}
+		fmt.Fprintf(w, "  Namespaces drifted:   %d of %d (%s)\n", len(s.DriftedNamespaces), len(s.Namespaces), drifted)
+	}
+	if s.Errors > 0 {
+		fmt.Fprintf(w, "  Lookup errors:        %d\n", s.Errors)
+	}
+	fmt.Fprintf(w, "  Differing keys:       %d (ONLY_IN_LOCAL: %d, ONLY_IN_DEPLOYED: %d, DIFFERENT: %d)\n",
+		s.OnlyInLocal+s.OnlyInDeployed+s.Different, s.OnlyInLocal, s.OnlyInDeployed, s.Different)
+}
+
+// FieldDifference is a mismatch in a resource field other than a data key
+type FieldDifference struct {
+	Field    string
+	Local    string
+	Deployed string
+}
+
+// HasDifferences reports whether any kind of drift was found
+func (r ComparisonResult) HasDifferences() bool {
+	return r.NotDeployed || len(r.Differences) > 0 || len(r.FinalizersOnlyInLocal) > 0 || len(r.FinalizersOnlyInDeployed) > 0 ||
+		len(r.FieldDifferences) > 0 || len(r.LabelDifferences) > 0 || len(r.AnnotationDifferences) > 0
+}
+
+// compareMetadata compares labels and annotations, leaving out annotations
+// matching ignoreAnnotations
+func compareMetadata(resource LocalResource, deployed *DeployedData, ignoreAnnotations []string) (labels, annotations []SecretDifference) {
+	labels = compare.Data(resource.GetLabels(), deployed.Labels, CompareOptions{})
+	annotations = compare.Data(resource.GetAnnotations(), deployed.Annotations, CompareOptions{IgnoreKeys: ignoreAnnotations})
+	return labels, annotations
+}
+
+// olderResourceVersion reports whether resourceVersion is older than min.
+// resourceVersions are opaque to clients, but the API server backed by etcd
+// issues increasing integers; anything else is never reported as older.
+func olderResourceVersion(resourceVersion, min string) bool {
+	version, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		debugf("Cannot compare resourceVersion '%s': %v", resourceVersion, err)
+		return false
+	}
+	minVersion, err := strconv.ParseUint(min, 10, 64)
+	if err != nil {
+		debugf("Cannot compare -min-resource-version '%s': %v", min, err)
+		return false
+	}
+	return version < minVersion
+}
+
+// compareSecretType compares Secret types, treating an unset local type as Opaque
+func compareSecretType(local, deployed string) *FieldDifference {
+	if local == "" {
+		local = "Opaque"
+	}
+	if deployed == "" {
+		deployed = "Opaque"
+	}
+	if local == deployed {
+		return nil
+	}
+	return &FieldDifference{Field: "type", Local: local, Deployed: deployed}
+}
+
+// compareImmutable compares the immutable flags, treating an unset flag as false
+func compareImmutable(local, deployed *bool) *FieldDifference {
+	isSet := func(b *bool) bool { return b != nil && *b }
+	if isSet(local) == isSet(deployed) {
+		return nil
 	}
+	return &FieldDifference{Field: "immutable", Local: strconv.FormatBool(isSet(local)), Deployed: strconv.FormatBool(isSet(deployed))}
 }
 
-// formatYAMLValue formats the value based on whether it's multiline.
-// If multiline, it uses the |- indicator; otherwise, it quotes the value.
-func formatYAMLValue(value string) string {
-	if strings.Contains(value, "\n") {
-		// Use |- for multiline strings
-		// Indent each line by 4 spaces
-		lines := strings.Split(value, "\n")
-		var formattedLines []string
-		for _, line := range lines {
-			formattedLines = append(formattedLines, "    "+line)
+// compareFinalizers returns the finalizers present only in the local list and
+// those present only in the deployed list. Ordering is not significant.
+func compareFinalizers(local, deployed []string) (onlyLocal, onlyDeployed []string) {
+	localSet := make(map[string]struct{})
+	for _, f := range local {
+		localSet[f] = struct{}{}
+	}
+	deployedSet := make(map[string]struct{})
+	for _, f := range deployed {
+		deployedSet[f] = struct{}{}
+	}
+
+	for _, f := range local {
+		if _, ok := deployedSet[f]; !ok {
+			onlyLocal = append(onlyLocal, f)
+		}
+	}
+	for _, f := range deployed {
+		if _, ok := localSet[f]; !ok {
+			onlyDeployed = append(onlyDeployed, f)
 		}
-		return "|-\n" + strings.Join(formattedLines, "\n")
 	}
-	// For single-line strings, quote the value
-	escapedValue := strings.ReplaceAll(value, "\"", "\\\"") // Escape double quotes
-	return fmt.Sprintf("\"%s\"", escapedValue)
+	return onlyLocal, onlyDeployed
+}
+
+// maskValue hides a value while keeping enough information to tell values apart
+func maskValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("<redacted: %d chars, sha256=%s...>", len(value), hex.EncodeToString(sum[:])[:12])
+}
+
+// binarySummary describes a binary value by its size and hash
+func binarySummary(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("<binary: %d bytes, sha256=%s...>", len(value), hex.EncodeToString(sum[:])[:12])
+}
+
+// largeSummary describes a value too large to print by its size and hash
+func largeSummary(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("<%d bytes, sha256=%s...>", len(value), hex.EncodeToString(sum[:])[:12])
 }
 
-// homeDir returns the home directory for the current user
-func homeDir() string {
-	if h := os.Getenv("HOME"); h != "" {
-		return h
+// isBinaryValue reports whether a value is not printable text: invalid UTF-8 or
+// control characters other than tabs and line breaks, which could corrupt a terminal
+func isBinaryValue(value *string) bool {
+	if value == nil {
+		return false
+	}
+	if !utf8.ValidString(*value) {
+		return true
+	}
+	for _, r := range *value {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return true
+		}
 	}
-	return os.Getenv("USERPROFILE") // Windows
+	return false
 }
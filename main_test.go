@@ -0,0 +1,693 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+func TestConfigMapData(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data:       map[string]string{"plain": "text"},
+		BinaryData: map[string][]byte{"bin": {0x00, 0x01, 0xff}},
+	}
+	deployed := configMapData(config)
+	wantData := map[string]string{"plain": "text", "bin": "\x00\x01\xff"}
+	if !reflect.DeepEqual(deployed.Data, wantData) {
+		t.Errorf("Data = %q, want %q", deployed.Data, wantData)
+	}
+	wantBinary := map[string]bool{"bin": true}
+	if !reflect.DeepEqual(deployed.BinaryKeys, wantBinary) {
+		t.Errorf("BinaryKeys = %v, want %v", deployed.BinaryKeys, wantBinary)
+	}
+	if deployed.Type != "configmap" || deployed.Name != "app" || deployed.Namespace != "default" {
+		t.Errorf("unexpected metadata %+v", deployed)
+	}
+}
+
+func TestCompareSecretType(t *testing.T) {
+	tests := []struct {
+		name            string
+		local, deployed string
+		want            *FieldDifference
+	}{
+		{"both unset", "", "", nil},
+		{"unset is Opaque", "", "Opaque", nil},
+		{"Opaque is unset", "Opaque", "", nil},
+		{"same type", "kubernetes.io/tls", "kubernetes.io/tls", nil},
+		{"different type", "Opaque", "kubernetes.io/tls", &FieldDifference{Field: "type", Local: "Opaque", Deployed: "kubernetes.io/tls"}},
+		{"unset against a type", "", "kubernetes.io/dockerconfigjson", &FieldDifference{Field: "type", Local: "Opaque", Deployed: "kubernetes.io/dockerconfigjson"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareSecretType(tt.local, tt.deployed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareSecretType(%q, %q) = %+v, want %+v", tt.local, tt.deployed, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTempFile writes content to a file named name in a temporary directory
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestParseList checks that each supported item of a "kind: List" document is
+// parsed, and unsupported items are skipped
+func TestParseList(t *testing.T) {
+	path := writeTempFile(t, "list-secrets.yaml", `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: db
+      namespace: prod
+    stringData:
+      password: hunter2
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: web
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: settings
+    data:
+      mode: fast
+`)
+	resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ kind, namespace, name string }{
+		{"Secret", "prod", "db"},
+		{"ConfigMap", "default", "settings"},
+	}
+	if len(resources) != len(want) {
+		t.Fatalf("parsed %d resources, want %d", len(resources), len(want))
+	}
+	for i, w := range want {
+		r := resources[i]
+		if r.GetKind() != w.kind || r.GetNamespace() != w.namespace || r.GetName() != w.name {
+			t.Errorf("resource %d = %s %s/%s, want %s %s/%s", i, r.GetKind(), r.GetNamespace(), r.GetName(), w.kind, w.namespace, w.name)
+		}
+	}
+	if got := resources[0].GetLocalData()["password"]; got != "hunter2" {
+		t.Errorf("Secret password = %q, want %q", got, "hunter2")
+	}
+	if got := resources[1].GetLocalData()["mode"]; got != "fast" {
+		t.Errorf("ConfigMap mode = %q, want %q", got, "fast")
+	}
+}
+
+func TestFilterResources(t *testing.T) {
+	config := &KubernetesConfig{Kind: "ConfigMap"}
+	config.Metadata.Name, config.Metadata.Namespace = "db-settings", "prod"
+	resources := []LocalResource{
+		testSecret("prod", "db-password"),
+		testSecret("staging", "db-password"),
+		testSecret("prod", "api-token"),
+		config,
+	}
+	names := func(resources []LocalResource) []string {
+		var out []string
+		for _, r := range resources {
+			out = append(out, r.GetKind()+" "+r.GetNamespace()+"/"+r.GetName())
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		filter ResourceFilter
+		want   []string
+	}{
+		{"empty filter matches everything", ResourceFilter{}, names(resources)},
+		{"exact name", ResourceFilter{Names: []string{"api-token"}}, []string{"Secret prod/api-token"}},
+		{"glob name", ResourceFilter{Names: []string{"db-*"}}, []string{"Secret prod/db-password", "Secret staging/db-password", "ConfigMap prod/db-settings"}},
+		{"several names", ResourceFilter{Names: []string{"api-*", "*-settings"}}, []string{"Secret prod/api-token", "ConfigMap prod/db-settings"}},
+		{"kind", ResourceFilter{Kinds: []string{"ConfigMap"}}, []string{"ConfigMap prod/db-settings"}},
+		{"name and kind", ResourceFilter{Names: []string{"db-*"}, Kinds: []string{"Secret"}}, []string{"Secret prod/db-password", "Secret staging/db-password"}},
+		{"namespace glob", ResourceFilter{Namespaces: []string{"stag*"}}, []string{"Secret staging/db-password"}},
+		{"no match", ResourceFilter{Names: []string{"missing"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(filterResources(resources, tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterResources() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBinaryValue(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name  string
+		value *string
+		want  bool
+	}{
+		{"nil", nil, false},
+		{"empty", str(""), false},
+		{"text", str("plain text"), false},
+		{"tabs and line breaks", str("a\tb\r\nc\n"), false},
+		{"unicode", str("naïve ✓ 日本語"), false},
+		{"null byte", str("abc\x00def"), true},
+		{"only null bytes", str("\x00\x00"), true},
+		{"escape sequence", str("\x1b[31mred"), true},
+		{"invalid utf-8", str("\xff\xfe"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBinaryValue(tt.value); got != tt.want {
+				t.Errorf("isBinaryValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.yaml", "secret.yaml", true},
+		{"*.yaml", "dev/secret.yaml", false},
+		{"**/*.yaml", "secret.yaml", true},
+		{"**/*.yaml", "dev/secret.yaml", true},
+		{"**/*.yaml", "envs/dev/secret.yaml", true},
+		{"envs/**/secret.yaml", "envs/secret.yaml", true},
+		{"envs/**/secret.yaml", "envs/dev/eu/secret.yaml", true},
+		{"envs/**/secret.yaml", "other/dev/secret.yaml", false},
+		{"envs/*/secret.yaml", "envs/dev/eu/secret.yaml", false},
+		{"**", "any/depth/file", true},
+		{"**/*secret*.yaml", "dev/config.yaml", false},
+	}
+	for _, tt := range tests {
+		if got := matchPathPattern(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPathPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// writeTree creates empty files at the given slash-separated paths under a
+// temporary directory and returns it
+func writeTree(t *testing.T, paths ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, p := range paths {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// relativePaths returns files relative to dir, with forward slashes
+func relativePaths(t *testing.T, dir string, files []string) []string {
+	t.Helper()
+	var rel []string
+	for _, file := range files {
+		r, err := filepath.Rel(dir, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestFindFilesDoubleStar(t *testing.T) {
+	dir := writeTree(t,
+		"secret.yaml",
+		"envs/dev/secret.yaml",
+		"envs/prod/eu/secret.yaml",
+		"envs/prod/eu/values.yaml",
+		"other/secret.yaml",
+		".git/secret.yaml",
+	)
+	tests := []struct {
+		name      string
+		pattern   string
+		recursive bool
+		want      []string
+	}{
+		{"top level only", "*secret*.yaml", false, []string{"secret.yaml"}},
+		{"double star at any depth", "**/secret.yaml", false, []string{"envs/dev/secret.yaml", "envs/prod/eu/secret.yaml", "other/secret.yaml", "secret.yaml"}},
+		{"double star under a directory", "envs/**/*.yaml", false, []string{"envs/dev/secret.yaml", "envs/prod/eu/secret.yaml", "envs/prod/eu/values.yaml"}},
+		{"single star is one level", "envs/*/secret.yaml", false, []string{"envs/dev/secret.yaml"}},
+		{"recursive matches base names", "secret.yaml", true, []string{"envs/dev/secret.yaml", "envs/prod/eu/secret.yaml", "other/secret.yaml", "secret.yaml"}},
+		{"patterns combined without duplicates", "**/secret.yaml,envs/**/*.yaml", false, []string{"envs/dev/secret.yaml", "envs/prod/eu/secret.yaml", "envs/prod/eu/values.yaml", "other/secret.yaml", "secret.yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := findFiles(dir, tt.pattern, tt.recursive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := relativePaths(t, dir, files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findFiles(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+	if _, err := findFiles(dir, "envs/[/*.yaml", false); err == nil {
+		t.Error("findFiles accepted an invalid pattern")
+	}
+}
+
+// helmOutput is `helm template` output mixing supported and unsupported kinds
+const helmOutput = `---
+# Source: app/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-credentials
+  namespace: prod
+type: Opaque
+stringData:
+  password: "hunter2"
+---
+# Source: app/templates/configmap.yaml
+
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-settings
+  namespace: prod
+data:
+  # Comments inside a document are kept out of the values
+  mode: fast
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+    - port: 80
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "app:1.0"
+---
+# Source: app/templates/empty.yaml
+`
+
+// TestParseHelmOutput parses rendered chart output, keeping the Secret and
+// ConfigMap and skipping the other kinds without warnings
+func TestParseHelmOutput(t *testing.T) {
+	path := writeTempFile(t, "rendered-secrets.yaml", helmOutput)
+	warningsBefore, errorsBefore := warningsLogged.Load(), errorsLogged.Load()
+	resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings, errors := warningsLogged.Load()-warningsBefore, errorsLogged.Load()-errorsBefore; warnings != 0 || errors != 0 {
+		t.Errorf("parsing logged %d warnings and %d errors, want none", warnings, errors)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("parsed %d resources, want 2", len(resources))
+	}
+	if r := resources[0]; r.GetKind() != "Secret" || r.GetName() != "app-credentials" || r.GetLocalData()["password"] != "hunter2" {
+		t.Errorf("unexpected first resource %s '%s': %q", r.GetKind(), r.GetName(), r.GetLocalData())
+	}
+	if r := resources[1]; r.GetKind() != "ConfigMap" || r.GetName() != "app-settings" || r.GetLocalData()["mode"] != "fast" {
+		t.Errorf("unexpected second resource %s '%s': %q", r.GetKind(), r.GetName(), r.GetLocalData())
+	}
+}
+
+func TestHelmSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{"comment above the document", "# Source: app/templates/deployment.yaml\nkind: Deployment\n", "app/templates/deployment.yaml"},
+		{"comment separated by a blank line", "# Source: app/templates/service.yaml\n\nkind: Service\n", "app/templates/service.yaml"},
+		{"among other comments", "# generated\n# Source: chart/templates/x.yaml\nkind: Service\n", "chart/templates/x.yaml"},
+		{"no source comment", "# just a comment\nkind: Service\n", ""},
+		{"no comments", "kind: Service\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.document), &node); err != nil {
+				t.Fatal(err)
+			}
+			if got := helmSource(&node); got != tt.want {
+				t.Errorf("helmSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		apiVersion   string
+		strict       bool
+		want         bool
+		wantWarnings int64
+		wantErrors   int64
+	}{
+		{"v1", "v1", false, true, 0, 0},
+		{"v1 under strict", "v1", true, true, 0, 0},
+		{"wrong group warns", "apps/v1", false, true, 1, 0},
+		{"missing warns", "", false, true, 1, 0},
+		{"wrong group under strict is skipped", "apps/v1", true, false, 0, 1},
+		{"wrong version under strict is skipped", "v2", true, false, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warningsBefore, errorsBefore := warningsLogged.Load(), errorsLogged.Load()
+			got := checkAPIVersion("Secret", tt.apiVersion, "db", "default", "secrets.yaml[doc 1]", ParseOptions{Strict: tt.strict})
+			if got != tt.want {
+				t.Errorf("checkAPIVersion(%q) = %v, want %v", tt.apiVersion, got, tt.want)
+			}
+			if warnings := warningsLogged.Load() - warningsBefore; warnings != tt.wantWarnings {
+				t.Errorf("logged %d warnings, want %d", warnings, tt.wantWarnings)
+			}
+			if errors := errorsLogged.Load() - errorsBefore; errors != tt.wantErrors {
+				t.Errorf("logged %d errors, want %d", errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+// TestParseAPIVersion checks that a Secret with a wrong apiVersion is kept
+// with a warning, and skipped under strict parsing
+func TestParseAPIVersion(t *testing.T) {
+	path := writeTempFile(t, "secrets.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: good
+stringData:
+  key: value
+---
+apiVersion: apps/v1
+kind: Secret
+metadata:
+  name: bad
+stringData:
+  key: value
+`)
+	for _, strict := range []bool{false, true} {
+		resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default", Strict: strict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range resources {
+			names = append(names, r.GetName())
+		}
+		want := []string{"good", "bad"}
+		if strict {
+			want = []string{"good"}
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("strict=%v: parsed %q, want %q", strict, names, want)
+		}
+	}
+}
+
+func TestCompareImmutable(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name            string
+		local, deployed *bool
+		want            *FieldDifference
+	}{
+		{"both unset", nil, nil, nil},
+		{"both true", &yes, &yes, nil},
+		{"unset is false", nil, &no, nil},
+		{"false is unset", &no, nil, nil},
+		{"true locally, unset deployed", &yes, nil, &FieldDifference{Field: "immutable", Local: "true", Deployed: "false"}},
+		{"unset locally, true deployed", nil, &yes, &FieldDifference{Field: "immutable", Local: "false", Deployed: "true"}},
+		{"false locally, true deployed", &no, &yes, &FieldDifference{Field: "immutable", Local: "false", Deployed: "true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareImmutable(tt.local, tt.deployed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareImmutable() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWarnNonCanonicalEncoding checks that a non-canonical encoding of a
+// matching value is warned about, but not one of a differing or ignored key
+func TestWarnNonCanonicalEncoding(t *testing.T) {
+	secret := testSecret("default", "db")
+	secret.StringData = nil
+	secret.Data = map[string]string{"user": "YWRtaW4", "pass": "c2VjcmV0", "token": "dG9rZW4"}
+	deployed := &DeployedData{Data: map[string]string{"user": "admin", "pass": "other", "token": "token"}}
+	opts := CompareOptions{IgnoreKeys: []string{"token"}}
+	differences := compare.Data(secret.GetLocalData(), deployed.Data, opts)
+	if len(differences) != 1 || differences[0].Key != "pass" {
+		t.Fatalf("unexpected differences %+v", differences)
+	}
+
+	warningsBefore := warningsLogged.Load()
+	warnNonCanonicalEncoding(secret, deployed, differences, opts)
+	if warnings := warningsLogged.Load() - warningsBefore; warnings != 1 {
+		t.Errorf("logged %d warnings, want 1 for key 'user'", warnings)
+	}
+}
+
+// TestParseNestedValue checks that a nested value under stringData or data is
+// reported as an error and dropped, keeping the other keys of the resource
+func TestParseNestedValue(t *testing.T) {
+	path := writeTempFile(t, "secrets.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+stringData:
+  user: admin
+  database:
+    host: db
+    port: 5432
+  hosts:
+    - a
+    - b
+  password: hunter2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+  limits:
+    cpu: 1
+`)
+	errorsBefore := errorsLogged.Load()
+	resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := errorsLogged.Load() - errorsBefore; errors != 3 {
+		t.Errorf("logged %d errors, want one per nested value (3)", errors)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("parsed %d resources, want 2", len(resources))
+	}
+	wantSecret := map[string]string{"user": "admin", "password": "hunter2"}
+	if got := resources[0].GetLocalData(); !reflect.DeepEqual(got, wantSecret) {
+		t.Errorf("Secret data = %q, want %q", got, wantSecret)
+	}
+	wantConfig := map[string]string{"mode": "fast"}
+	if got := resources[1].GetLocalData(); !reflect.DeepEqual(got, wantConfig) {
+		t.Errorf("ConfigMap data = %q, want %q", got, wantConfig)
+	}
+}
+
+func TestFindFilesInDirs(t *testing.T) {
+	root := writeTree(t,
+		"secrets/app-secret.yaml",
+		"secrets/nested/db-secret.yaml",
+		"configs/app-config.yaml",
+		"configs/notes.txt",
+	)
+	secrets, configs := filepath.Join(root, "secrets"), filepath.Join(root, "configs")
+	tests := []struct {
+		name      string
+		dirs      []string
+		recursive bool
+		want      []string
+	}{
+		{"single directory", []string{secrets}, false, []string{"secrets/app-secret.yaml"}},
+		{"two directories", []string{secrets, configs}, false, []string{"configs/app-config.yaml", "secrets/app-secret.yaml"}},
+		{"two directories recursive", []string{secrets, configs}, true, []string{"configs/app-config.yaml", "secrets/app-secret.yaml", "secrets/nested/db-secret.yaml"}},
+		{"overlapping roots are de-duplicated", []string{root, secrets, filepath.Join(secrets, "nested")}, true, []string{"configs/app-config.yaml", "secrets/app-secret.yaml", "secrets/nested/db-secret.yaml"}},
+		{"same directory twice", []string{configs, configs + string(filepath.Separator)}, false, []string{"configs/app-config.yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := findFilesInDirs(tt.dirs, "*secret*.yaml,*config*.yaml", tt.recursive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := relativePaths(t, root, files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findFilesInDirs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := findFilesInDirs([]string{secrets, filepath.Join(root, "missing")}, "*.yaml", true); err == nil {
+		t.Error("missing directory was not reported")
+	}
+}
+
+// TestParseTwoDirectories parses the files found in two directories, each
+// contributing one resource
+func TestParseTwoDirectories(t *testing.T) {
+	secretPath := writeTempFile(t, "app-secret.yaml", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\nstringData:\n  password: hunter2\n")
+	configPath := writeTempFile(t, "app-config.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  mode: fast\n")
+	files, err := findFilesInDirs([]string{filepath.Dir(secretPath), filepath.Dir(configPath)}, "*secret*.yaml,*config*.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, file := range files {
+		resources, err := parseYAMLResources(file, ParseOptions{DefaultNamespace: "default"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range resources {
+			kinds = append(kinds, r.GetKind())
+		}
+	}
+	sort.Strings(kinds)
+	if want := []string{"ConfigMap", "Secret"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("parsed %q from %q, want %q", kinds, files, want)
+	}
+}
+
+// TestParseOverlappingKeys checks that a key set in both data and stringData is
+// compared with its stringData value and warned about
+func TestParseOverlappingKeys(t *testing.T) {
+	path := writeTempFile(t, "secrets.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: b2xk
+  user: YWRtaW4=
+stringData:
+  password: new
+`)
+	warningsBefore := warningsLogged.Load()
+	resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings := warningsLogged.Load() - warningsBefore; warnings != 1 {
+		t.Errorf("logged %d warnings, want 1 for key 'password'", warnings)
+	}
+	if len(resources) != 1 {
+		t.Fatalf("parsed %d resources, want 1", len(resources))
+	}
+	want := map[string]string{"password": "new", "user": "admin"}
+	if got := resources[0].GetLocalData(); !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %q, want %q", got, want)
+	}
+}
+
+// TestParseUnpaddedBase64 checks that base64 without padding is compared by
+// default, and skipped with an error under strict parsing (and -lint)
+func TestParseUnpaddedBase64(t *testing.T) {
+	path := writeTempFile(t, "secrets.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: unpadded
+data:
+  user: YWRtaW4
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: padded
+data:
+  user: YWRtaW4=
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: binary
+binaryData:
+  bin: AAE
+`)
+	tests := []struct {
+		strict     bool
+		want       []string
+		wantErrors int64
+	}{
+		{false, []string{"unpadded", "padded", "binary"}, 0},
+		{true, []string{"padded"}, 2},
+	}
+	for _, tt := range tests {
+		errorsBefore := errorsLogged.Load()
+		resources, err := parseYAMLResources(path, ParseOptions{DefaultNamespace: "default", Strict: tt.strict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range resources {
+			names = append(names, r.GetName())
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("strict=%v: parsed %q, want %q", tt.strict, names, tt.want)
+		}
+		if errors := errorsLogged.Load() - errorsBefore; errors != tt.wantErrors {
+			t.Errorf("strict=%v: logged %d errors, want %d", tt.strict, errors, tt.wantErrors)
+		}
+	}
+}
+
+// TestManagedByDeterministic checks that a glob marker matching several
+// annotations always reports the same one
+func TestManagedByDeterministic(t *testing.T) {
+	deployed := &DeployedData{Annotations: map[string]string{
+		"reconcile.external-secrets.io/managed": "true",
+		"reconcile.external-secrets.io/data":    "hash",
+		"reconcile.external-secrets.io/zone":    "eu",
+	}}
+	markers := parseManagedMarkers(defaultManagedMarkers)
+	for i := 0; i < 20; i++ {
+		if got, want := managedBy(deployed, markers), "annotation reconcile.external-secrets.io/data=hash"; got != want {
+			t.Fatalf("managedBy() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+// defaultManagedMarkers are the labels and annotations that mark a deployed
+// resource as written by an operator rather than applied from a manifest
+const defaultManagedMarkers = "app.kubernetes.io/managed-by!=Helm,reconcile.external-secrets.io/*,cert-manager.io/certificate-name"
+
+// managedMarker matches a label or annotation: "key" matches any value,
+// "key=value" a single value and "key!=value" any other value. The key may be
+// a glob pattern.
+type managedMarker struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// parseManagedMarkers parses a comma-separated list of markers
+func parseManagedMarkers(value string) []managedMarker {
+	var markers []managedMarker
+	for _, item := range splitList(value) {
+		var marker managedMarker
+		switch {
+		case strings.Contains(item, "!="):
+			parts := strings.SplitN(item, "!=", 2)
+			marker = managedMarker{key: parts[0], value: parts[1], negate: true}
+		case strings.Contains(item, "="):
+			parts := strings.SplitN(item, "=", 2)
+			marker = managedMarker{key: parts[0], value: parts[1]}
+		default:
+			marker = managedMarker{key: item}
+		}
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
+// matches returns the first label or annotation matching the marker, in key
+// order so a glob matching several keys always names the same one, as key=value
+func (m managedMarker) matches(fields map[string]string) (string, bool) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := fields[key]
+		if !compare.MatchesAnyPattern(key, []string{m.key}) {
+			continue
+		}
+		if m.value == "" && !m.negate || (value == m.value) != m.negate {
+			return key + "=" + value, true
+		}
+	}
+	return "", false
+}
+
+// managedBy describes why a deployed resource looks operator-managed: a
+// controller owner reference or a matching marker. It returns "" otherwise.
+func managedBy(deployed *DeployedData, markers []managedMarker) string {
+	if deployed.Controller != "" {
+		return "controller " + deployed.Controller
+	}
+	for _, marker := range markers {
+		if match, ok := marker.matches(deployed.Labels); ok {
+			return "label " + match
+		}
+		if match, ok := marker.matches(deployed.Annotations); ok {
+			return "annotation " + match
+		}
+	}
+	return ""
+}
+
+// controllerOf returns the controller owner of an object as Kind/name, or ""
+func controllerOf(owners []metav1.OwnerReference) string {
+	for _, owner := range owners {
+		if owner.Controller != nil && *owner.Controller {
+			return fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resourceOverride is the cluster name and namespace a local resource is looked up by
+type resourceOverride struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// resourceMapping is the content of a -mapping file. Files maps file paths or
+// globs to an override for every resource in the matching files; Names maps
+// manifest names, e.g. template placeholders, to an override for that resource.
+type resourceMapping struct {
+	Files map[string]resourceOverride `yaml:"files"`
+	Names map[string]resourceOverride `yaml:"names"`
+}
+
+// loadMapping reads a YAML or JSON -mapping file
+func loadMapping(filePath string) (*resourceMapping, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mapping file: %w", err)
+	}
+	var mapping resourceMapping
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&mapping); err != nil {
+		return nil, fmt.Errorf("error decoding mapping file '%s': %w", filePath, err)
+	}
+	for pattern := range mapping.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid file pattern '%s' in mapping file '%s': %w", pattern, filePath, err)
+		}
+	}
+	return &mapping, nil
+}
+
+// apply returns the resources of a file with their overrides applied. A file
+// override applies first, so a name override is more specific and wins.
+func (m *resourceMapping) apply(resources []LocalResource, file string) []LocalResource {
+	fileOverride, fileMatched := m.fileOverride(file)
+	mapped := make([]LocalResource, len(resources))
+	for i, resource := range resources {
+		if fileMatched {
+			resource = withOverride(resource, fileOverride, file)
+		}
+		if override, ok := m.Names[resource.GetName()]; ok {
+			resource = withOverride(resource, override, file)
+		}
+		mapped[i] = resource
+	}
+	return mapped
+}
+
+// fileOverride returns the override for a file, matching the patterns against
+// its path and, for patterns without a slash, its base name. When several
+// patterns match, the first in sorted order is used.
+func (m *resourceMapping) fileOverride(file string) (resourceOverride, bool) {
+	patterns := make([]string, 0, len(m.Files))
+	for pattern := range m.Files {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	slashed := filepath.ToSlash(filepath.Clean(file))
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(path.Clean(pattern), slashed); matched {
+			return m.Files[pattern], true
+		}
+		if matched, _ := path.Match(pattern, filepath.Base(file)); matched {
+			return m.Files[pattern], true
+		}
+	}
+	return resourceOverride{}, false
+}
+
+// withOverride returns a copy of a local resource with the name and namespace
+// of override, where set
+func withOverride(resource LocalResource, override resourceOverride, file string) LocalResource {
+	name, namespace := resource.GetName(), resource.GetNamespace()
+	if override.Name != "" {
+		name = override.Name
+	}
+	if override.Namespace != "" {
+		namespace = override.Namespace
+	}
+	debugf("Mapping %s '%s' in namespace '%s' from file '%s' to '%s' in namespace '%s'", resource.GetKind(), resource.GetName(), resource.GetNamespace(), filepath.Base(file), name, namespace)
+	switch r := resource.(type) {
+	case *KubernetesSecret:
+		copied := *r
+		copied.Metadata.Name, copied.Metadata.Namespace = name, namespace
+		return &copied
+	case *KubernetesConfig:
+		copied := *r
+		copied.Metadata.Name, copied.Metadata.Namespace = name, namespace
+		return &copied
+	default:
+		return resource
+	}
+}
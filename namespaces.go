@@ -0,0 +1,30 @@
+package main
+
+// expandNamespaces returns a copy of every resource for each namespace, with
+// the manifest's namespace replaced. Resources are grouped by namespace, in
+// the order the namespaces are given, and keep their file order within it.
+func expandNamespaces(resources []LocalResource, namespaces []string) []LocalResource {
+	expanded := make([]LocalResource, 0, len(resources)*len(namespaces))
+	for _, namespace := range namespaces {
+		for _, resource := range resources {
+			expanded = append(expanded, withNamespace(resource, namespace))
+		}
+	}
+	return expanded
+}
+
+// withNamespace returns a copy of a local resource placed in namespace
+func withNamespace(resource LocalResource, namespace string) LocalResource {
+	switch r := resource.(type) {
+	case *KubernetesSecret:
+		copied := *r
+		copied.Metadata.Namespace = namespace
+		return &copied
+	case *KubernetesConfig:
+		copied := *r
+		copied.Metadata.Namespace = namespace
+		return &copied
+	default:
+		return resource
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// findOrphans lists the Secrets and ConfigMaps in every namespace referenced by
+// the local resources and describes the deployed ones matching filter that have
+// no local manifest. Resources the cluster or Helm create are left out.
+func findOrphans(ctx context.Context, clientset kubernetes.Interface, resources []LocalResource, filter ResourceFilter, timeout time.Duration) ([]string, error) {
+	local := make(map[string]bool, len(resources))
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, resource := range resources {
+		local[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())] = true
+		if !seen[resource.GetNamespace()] {
+			seen[resource.GetNamespace()] = true
+			namespaces = append(namespaces, resource.GetNamespace())
+		}
+	}
+	sort.Strings(namespaces)
+
+	var orphans []string
+	for _, namespace := range namespaces {
+		listCtx, cancel := context.WithTimeout(ctx, timeout)
+		deployed, err := listClusterResources(listCtx, clientset, namespace)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("error listing resources in namespace '%s': %w", namespace, err)
+		}
+		for _, resource := range filterResources(deployed, filter) {
+			if local[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())] {
+				continue
+			}
+			if isClusterManaged(resource) {
+				debugf("Not reporting %s '%s' in namespace '%s' as an orphan: it is created by the cluster or Helm", resource.GetKind(), resource.GetName(), namespace)
+				continue
+			}
+			orphans = append(orphans, fmt.Sprintf("%s '%s' in namespace '%s'", resource.GetKind(), resource.GetName(), namespace))
+		}
+	}
+	return orphans, nil
+}
+
+// isClusterManaged reports whether a deployed resource is created by Kubernetes
+// or Helm rather than from a manifest
+func isClusterManaged(resource LocalResource) bool {
+	if resource.GetKind() == "ConfigMap" {
+		return resource.GetName() == "kube-root-ca.crt"
+	}
+	switch resource.GetType() {
+	case string(corev1.SecretTypeServiceAccountToken), "helm.sh/release.v1":
+		return true
+	}
+	return false
+}
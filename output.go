@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// openOutputFile creates or truncates the -output-file, creating its parent
+// directories. The report is then written to the file and, with tee, also to stdout.
+// Writes to the file are unbuffered, so nothing is lost when the run exits early.
+func openOutputFile(path string, tee bool) (io.Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file: %w", err)
+	}
+	if tee {
+		return io.MultiWriter(os.Stdout, f), nil
+	}
+	return f, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+// renderPatch renders a YAML document with the keys that need to change locally
+// to match the deployed resource, nesting with indent spaces per level. It returns
+// an empty string when nothing needs to change.
+func renderPatch(result ComparisonResult, indent int) string {
+	pad := strings.Repeat(" ", indent)
+	var sb strings.Builder
+	for _, diff := range result.Differences {
+		// Keys only present locally need no change to match, and binary values can't go into data/stringData
+		if diff.Deployed == nil || diff.Binary {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s%s: %s\n", pad, diff.Key, compare.FormatYAMLValue(*diff.Deployed, indent))
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("apiVersion: v1\nkind: %s\nmetadata:\n%sname: %s\n%snamespace: %s\n%s:\n%s",
+		result.Kind, pad, result.Name, pad, result.Namespace, result.MergeField, sb.String())
+}
+
+// writePatchFile writes the patch documents as a single multi-document YAML file.
+// An existing file is only replaced when force is set.
+func writePatchFile(path string, docs []string, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("patch file '%s' already exists (use -force to overwrite)", path)
+		}
+		return fmt.Errorf("error creating patch file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(strings.Join(docs, "---\n")); err != nil {
+		return fmt.Errorf("error writing patch file: %w", err)
+	}
+	return nil
+}
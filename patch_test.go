@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestRenderPatchIndent renders patches with single and multi-line values at
+// several indent widths and checks they parse back to the deployed values
+func TestRenderPatchIndent(t *testing.T) {
+	str := func(s string) *string { return &s }
+	result := ComparisonResult{
+		Kind:       "Secret",
+		Name:       "app",
+		Namespace:  "default",
+		MergeField: "stringData",
+		Differences: []SecretDifference{
+			{Key: "config.yaml", Local: str("a: 1\n"), Deployed: str("server:\n  port: 8080\n")},
+			{Key: "key.pem", Deployed: str("-----BEGIN KEY-----\nabc\n-----END KEY-----")},
+			{Key: "only-local", Local: str("dropped")},
+			{Key: "password", Local: str("old"), Deployed: str(`new "quoted": value`)},
+		},
+	}
+	want := map[string]string{
+		"config.yaml": "server:\n  port: 8080\n",
+		"key.pem":     "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"password":    `new "quoted": value`,
+	}
+	for _, indent := range []int{2, 4, 8} {
+		t.Run(fmt.Sprintf("indent %d", indent), func(t *testing.T) {
+			patch := renderPatch(result, indent)
+			pad := strings.Repeat(" ", indent)
+			for _, line := range []string{pad + "name: app", pad + "namespace: default", pad + "config.yaml: |\n" + pad + pad + "server:\n" + pad + pad + "  port: 8080"} {
+				if !strings.Contains(patch, line) {
+					t.Errorf("patch does not contain %q:\n%s", line, patch)
+				}
+			}
+			var parsed struct {
+				Kind     string `yaml:"kind"`
+				Metadata struct {
+					Name      string `yaml:"name"`
+					Namespace string `yaml:"namespace"`
+				} `yaml:"metadata"`
+				StringData map[string]string `yaml:"stringData"`
+			}
+			if err := yaml.Unmarshal([]byte(patch), &parsed); err != nil {
+				t.Fatalf("patch does not parse: %v\n%s", err, patch)
+			}
+			if parsed.Kind != "Secret" || parsed.Metadata.Name != "app" || parsed.Metadata.Namespace != "default" {
+				t.Errorf("unexpected metadata %+v", parsed)
+			}
+			if len(parsed.StringData) != len(want) {
+				t.Errorf("patch has keys %q, want %q", parsed.StringData, want)
+			}
+			for key, value := range want {
+				if parsed.StringData[key] != value {
+					t.Errorf("%s = %q, want %q", key, parsed.StringData[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPatchNothingToChange(t *testing.T) {
+	local := "only here"
+	result := ComparisonResult{Kind: "Secret", Name: "app", Namespace: "default", MergeField: "stringData",
+		Differences: []SecretDifference{{Key: "local-key", Local: &local}}}
+	if patch := renderPatch(result, 2); patch != "" {
+		t.Errorf("renderPatch() = %q, want empty", patch)
+	}
+}
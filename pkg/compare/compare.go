@@ -0,0 +1,255 @@
+// Package compare holds the drift-detection logic of secret-compare: the local
+// Secret and ConfigMap manifest types and the comparison of their values with
+// the deployed ones. It has no dependency on a Kubernetes client.
+package compare
+
+import (
+	"crypto/sha256"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SecretDifference represents a difference in a key-value pair
+type SecretDifference struct {
+	Key      string
+	Local    *string
+	Deployed *string
+	Binary   bool     // values are binary and summarized rather than printed
+	Notes    []string // human-readable details of the change, e.g. for TLS certificates
+}
+
+// Options controls how values are compared by Data
+type Options struct {
+	// Equivalences maps a value to the id of its equivalence class.
+	// Values sharing a class id are treated as equal.
+	Equivalences map[string]int
+	// OnlyKeys, when set, restricts the comparison to keys matching one of its
+	// names or glob patterns. It is applied before IgnoreKeys.
+	OnlyKeys []string
+	// IgnoreKeys holds key names or glob patterns excluded from the comparison
+	IgnoreKeys []string
+	// IgnoreTrailingNewline trims a single trailing newline from both sides before comparing
+	IgnoreTrailingNewline bool
+	// NormalizeWhitespace compares values after converting CRLF to LF and
+	// trimming trailing spaces and tabs from every line
+	NormalizeWhitespace bool
+	// NormalizePEM compares values made of PEM blocks after re-wrapping them
+	// to 64 columns, so differences in line-wrapping width are ignored
+	NormalizePEM bool
+	// ShowNormalized reports the whitespace-normalized values instead of the raw ones
+	ShowNormalized bool
+	// Semantic compares values that parse as YAML or JSON mappings or lists by
+	// their parsed structure instead of their text
+	Semantic bool
+	// IgnoreEmpty treats a key with an empty value as equal to a missing key
+	IgnoreEmpty bool
+	// Logger receives notes on differences that were suppressed; nil discards them
+	Logger Logger
+}
+
+// Logger receives the informational messages of a comparison
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+}
+
+// debugf logs through the configured Logger, if any
+func (o Options) debugf(format string, args ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Debugf(format, args...)
+	}
+}
+
+// infof logs through the configured Logger, if any
+func (o Options) infof(format string, args ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Infof(format, args...)
+	}
+}
+
+// MatchesAnyPattern reports whether key equals or glob-matches any of the patterns.
+// Patterns are not ordered: a key matching several patterns is treated the same as one matching a single pattern.
+func MatchesAnyPattern(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if key == pattern {
+			return true
+		}
+		if matched, err := path.Match(pattern, key); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize applies the configured normalizations to a value before comparison
+func (o Options) Normalize(value string) string {
+	if o.IgnoreTrailingNewline {
+		value = strings.TrimSuffix(value, "\n")
+	}
+	return value
+}
+
+// Canonical returns the form of a value used for equality checks. Unlike
+// Normalize, it does not change the values shown in the report by default.
+func (o Options) Canonical(value string) string {
+	if o.NormalizePEM {
+		value = canonicalPEM(value)
+	}
+	if !o.NormalizeWhitespace {
+		return value
+	}
+	lines := strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Display returns the form of a value shown in reports and snippets: the value
+// after Normalize, and in its Canonical form as well when ShowNormalized is set
+func (o Options) Display(value string) string {
+	value = o.Normalize(value)
+	if o.ShowNormalized {
+		value = o.Canonical(value)
+	}
+	return value
+}
+
+// largeValueSize is the size from which whitespace-normalized values are
+// compared by a streaming hash instead of building their canonical copies
+const largeValueSize = 64 * 1024
+
+// canonicalEqual reports whether two values have the same canonical form
+func (o Options) canonicalEqual(a, b string) bool {
+	if o.NormalizePEM {
+		a, b = canonicalPEM(a), canonicalPEM(b)
+	}
+	if a == b || !o.NormalizeWhitespace {
+		return a == b
+	}
+	if len(a) < largeValueSize && len(b) < largeValueSize {
+		return o.Canonical(a) == o.Canonical(b)
+	}
+	return whitespaceDigest(a) == whitespaceDigest(b)
+}
+
+// whitespaceDigest hashes the whitespace-normalized form of value (see Canonical)
+// line by line through a fixed buffer, so multi-megabyte values are not copied
+func whitespaceDigest(value string) [sha256.Size]byte {
+	h := sha256.New()
+	buf := make([]byte, 0, 32*1024)
+	for {
+		line, rest, found := strings.Cut(value, "\n")
+		if found {
+			line = strings.TrimSuffix(line, "\r")
+		}
+		line = strings.TrimRight(line, " \t")
+		for len(line) > 0 {
+			if len(buf) == cap(buf) {
+				h.Write(buf)
+				buf = buf[:0]
+			}
+			n := copy(buf[len(buf):cap(buf)], line)
+			buf, line = buf[:len(buf)+n], line[n:]
+		}
+		if !found {
+			break
+		}
+		if len(buf) == cap(buf) {
+			h.Write(buf)
+			buf = buf[:0]
+		}
+		buf = append(buf, '\n')
+		value = rest
+	}
+	h.Write(buf)
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// Equivalent reports whether two values belong to the same equivalence class
+func (o Options) Equivalent(a, b string) bool {
+	classA, okA := o.Equivalences[a]
+	classB, okB := o.Equivalences[b]
+	return okA && okB && classA == classB
+}
+
+// Includes reports whether a key takes part in the comparison according to
+// OnlyKeys and IgnoreKeys
+func (o Options) Includes(key string) bool {
+	if len(o.OnlyKeys) > 0 && !MatchesAnyPattern(key, o.OnlyKeys) {
+		return false
+	}
+	return !MatchesAnyPattern(key, o.IgnoreKeys)
+}
+
+// Data compares the local data with the deployed data and returns differences sorted by key
+func Data(local, deployed map[string]string, opts Options) []SecretDifference {
+	var differences []SecretDifference
+
+	// Create a set of all keys
+	keysSet := make(map[string]struct{})
+	for key := range local {
+		keysSet[key] = struct{}{}
+	}
+	for key := range deployed {
+		keysSet[key] = struct{}{}
+	}
+
+	for key := range keysSet {
+		if !opts.Includes(key) {
+			continue
+		}
+		localVal, localExists := local[key]
+		deployedVal, deployedExists := deployed[key]
+		localVal, deployedVal = opts.Display(localVal), opts.Display(deployedVal)
+
+		if opts.IgnoreEmpty && ((!localExists && deployedVal == "") || (!deployedExists && localVal == "")) {
+			opts.debugf("Key '%s' is empty on one side and missing on the other (-ignore-empty)", key)
+			continue
+		}
+
+		if !localExists && deployedExists {
+			diff := SecretDifference{
+				Key:      key,
+				Local:    nil,
+				Deployed: &deployedVal,
+			}
+			differences = append(differences, diff)
+		} else if localExists && !deployedExists {
+			diff := SecretDifference{
+				Key:      key,
+				Local:    &localVal,
+				Deployed: nil,
+			}
+			differences = append(differences, diff)
+		} else if localExists && deployedExists && localVal != deployedVal && !opts.canonicalEqual(localVal, deployedVal) {
+			if opts.Equivalent(localVal, deployedVal) {
+				opts.infof("Equivalence rule suppressed difference for key '%s': local %q and deployed %q are equivalent", key, localVal, deployedVal)
+				continue
+			}
+			var notes []string
+			if opts.Semantic {
+				changes, structured := compareStructured(localVal, deployedVal)
+				if structured && len(changes) == 0 {
+					opts.debugf("Key '%s' differs only in formatting; its parsed content is equal (-semantic)", key)
+					continue
+				}
+				notes = changes
+			}
+			diff := SecretDifference{
+				Key:      key,
+				Local:    &localVal,
+				Deployed: &deployedVal,
+				Notes:    notes,
+			}
+			differences = append(differences, diff)
+		}
+	}
+
+	// Keys come from a map; sort them so every report is deterministic
+	sort.Slice(differences, func(i, j int) bool { return differences[i].Key < differences[j].Key })
+	return differences
+}
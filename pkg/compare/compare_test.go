@@ -0,0 +1,405 @@
+package compare
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestData(t *testing.T) {
+	tests := []struct {
+		name     string
+		local    map[string]string
+		deployed map[string]string
+		opts     Options
+		want     []SecretDifference
+	}{
+		{
+			name:     "equal",
+			local:    map[string]string{"a": "1", "b": "2"},
+			deployed: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:     "different, only local and only deployed, sorted by key",
+			local:    map[string]string{"c": "local", "b": "2", "a": "1"},
+			deployed: map[string]string{"c": "deployed", "d": "4", "a": "1"},
+			want: []SecretDifference{
+				{Key: "b", Local: strPtr("2")},
+				{Key: "c", Local: strPtr("local"), Deployed: strPtr("deployed")},
+				{Key: "d", Deployed: strPtr("4")},
+			},
+		},
+		{
+			name:     "only keys and ignore keys",
+			local:    map[string]string{"db.user": "a", "db.pass": "b", "api": "c"},
+			deployed: map[string]string{"db.user": "x", "db.pass": "y", "api": "z"},
+			opts:     Options{OnlyKeys: []string{"db.*"}, IgnoreKeys: []string{"db.pass"}},
+			want:     []SecretDifference{{Key: "db.user", Local: strPtr("a"), Deployed: strPtr("x")}},
+		},
+		{
+			name:     "ignore keys by name and glob",
+			local:    map[string]string{"ca.crt": "a", "token-1": "b", "user": "c"},
+			deployed: map[string]string{"ca.crt": "x", "token-2": "y", "user": "z"},
+			opts:     Options{IgnoreKeys: []string{"ca.crt", "token-*"}},
+			want:     []SecretDifference{{Key: "user", Local: strPtr("c"), Deployed: strPtr("z")}},
+		},
+		{
+			name:     "trailing newline ignored",
+			local:    map[string]string{"a": "value\n"},
+			deployed: map[string]string{"a": "value"},
+			opts:     Options{IgnoreTrailingNewline: true},
+		},
+		{
+			name:     "trailing newline counts by default",
+			local:    map[string]string{"a": "value\n"},
+			deployed: map[string]string{"a": "value"},
+			want:     []SecretDifference{{Key: "a", Local: strPtr("value\n"), Deployed: strPtr("value")}},
+		},
+		{
+			name:     "whitespace normalized",
+			local:    map[string]string{"a": "x  \r\ny"},
+			deployed: map[string]string{"a": "x\ny"},
+			opts:     Options{NormalizeWhitespace: true},
+		},
+		{
+			name:     "equivalent values",
+			local:    map[string]string{"flag": "yes"},
+			deployed: map[string]string{"flag": "true"},
+			opts:     Options{Equivalences: map[string]int{"yes": 0, "true": 0}},
+		},
+		{
+			name:     "empty local value against missing deployed key",
+			local:    map[string]string{"a": "", "b": "1"},
+			deployed: map[string]string{"b": "1"},
+			opts:     Options{IgnoreEmpty: true},
+		},
+		{
+			name:     "missing local key against empty deployed value",
+			local:    map[string]string{"b": "1"},
+			deployed: map[string]string{"b": "1", "c": ""},
+			opts:     Options{IgnoreEmpty: true},
+		},
+		{
+			name:     "empty value against non-empty value still differs",
+			local:    map[string]string{"a": ""},
+			deployed: map[string]string{"a": "set"},
+			opts:     Options{IgnoreEmpty: true},
+			want:     []SecretDifference{{Key: "a", Local: strPtr(""), Deployed: strPtr("set")}},
+		},
+		{
+			name:     "non-empty value against missing key still differs",
+			local:    map[string]string{"a": "set"},
+			deployed: map[string]string{"b": "other"},
+			opts:     Options{IgnoreEmpty: true},
+			want:     []SecretDifference{{Key: "a", Local: strPtr("set")}, {Key: "b", Deployed: strPtr("other")}},
+		},
+		{
+			name:     "empty local value against missing deployed key counts by default",
+			local:    map[string]string{"a": ""},
+			deployed: map[string]string{},
+			want:     []SecretDifference{{Key: "a", Local: strPtr("")}},
+		},
+		{
+			name:     "missing local key against empty deployed value counts by default",
+			local:    map[string]string{},
+			deployed: map[string]string{"a": ""},
+			want:     []SecretDifference{{Key: "a", Deployed: strPtr("")}},
+		},
+		{
+			name:     "semantic formatting only",
+			local:    map[string]string{"config.json": `{"a": 1, "b": [1, 2]}`},
+			deployed: map[string]string{"config.json": "b:\n  - 1\n  - 2\na: 1\n"},
+			opts:     Options{Semantic: true},
+		},
+		{
+			name:     "semantic change is annotated",
+			local:    map[string]string{"app.yaml": "server:\n  port: 80\n"},
+			deployed: map[string]string{"app.yaml": "server:\n  port: 8080\n"},
+			opts:     Options{Semantic: true},
+			want: []SecretDifference{{
+				Key:      "app.yaml",
+				Local:    strPtr("server:\n  port: 80\n"),
+				Deployed: strPtr("server:\n  port: 8080\n"),
+				Notes:    []string{"field server.port changed"},
+			}},
+		},
+		{
+			name:     "semantic compares multi-document values as text",
+			local:    map[string]string{"v": "a: 1\n---\nb: 2\n"},
+			deployed: map[string]string{"v": "a: 1\n---\nb: 3\n"},
+			opts:     Options{Semantic: true},
+			want:     []SecretDifference{{Key: "v", Local: strPtr("a: 1\n---\nb: 2\n"), Deployed: strPtr("a: 1\n---\nb: 3\n")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Data(tt.local, tt.deployed, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Data() = %s, want %s", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+// TestDataSortedByKey checks that differences come out sorted by key on every
+// run, although the input maps are iterated in random order
+func TestDataSortedByKey(t *testing.T) {
+	local, deployed := make(map[string]string), make(map[string]string)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key-%02d", i)
+		switch i % 3 {
+		case 0:
+			local[key] = "only local"
+		case 1:
+			deployed[key] = "only deployed"
+		default:
+			local[key], deployed[key] = "local", "deployed"
+		}
+	}
+	for run := 0; run < 20; run++ {
+		differences := Data(local, deployed, Options{})
+		if len(differences) != 50 {
+			t.Fatalf("got %d differences, want 50", len(differences))
+		}
+		for i, diff := range differences {
+			if want := fmt.Sprintf("key-%02d", i); diff.Key != want {
+				t.Fatalf("run %d: difference %d is %q, want %q", run, i, diff.Key, want)
+			}
+		}
+	}
+}
+
+// describe renders differences readably for test failures
+func describe(differences []SecretDifference) string {
+	value := func(v *string) string {
+		if v == nil {
+			return "<nil>"
+		}
+		return "\"" + *v + "\""
+	}
+	out := "["
+	for _, diff := range differences {
+		out += "{" + diff.Key + " " + value(diff.Local) + " " + value(diff.Deployed)
+		for _, note := range diff.Notes {
+			out += " note:" + note
+		}
+		out += "}"
+	}
+	return out + "]"
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value string
+		opts  Options
+		want  string
+	}{
+		{"value\n", Options{}, "value\n"},
+		{"value\n", Options{IgnoreTrailingNewline: true}, "value"},
+		{"value\n\n", Options{IgnoreTrailingNewline: true}, "value\n"},
+		{"value", Options{IgnoreTrailingNewline: true}, "value"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Normalize(tt.value); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		value string
+		opts  Options
+		want  string
+	}{
+		{"a \r\nb\t", Options{}, "a \r\nb\t"},
+		{"a \r\nb\t", Options{NormalizeWhitespace: true}, "a\nb"},
+		{"  indented\n\nblank", Options{NormalizeWhitespace: true}, "  indented\n\nblank"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Canonical(tt.value); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	opts := Options{Equivalences: map[string]int{"yes": 0, "true": 0, "no": 1, "false": 1}}
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"yes", "true", true},
+		{"no", "false", true},
+		{"yes", "false", false},
+		{"yes", "other", false},
+		{"other", "other", false},
+	}
+	for _, tt := range tests {
+		if got := opts.Equivalent(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equivalent(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesAnyPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		patterns []string
+		want     bool
+	}{
+		{"exact match", "ca.crt", []string{"ca.crt"}, true},
+		{"glob match", "token-abc", []string{"token-*"}, true},
+		{"glob character class", "key1", []string{"key[0-9]"}, true},
+		{"no match", "password", []string{"ca.crt", "token-*"}, false},
+		{"glob does not match a prefix only", "my-token-abc", []string{"token-*"}, false},
+		{"matching several patterns", "token-ca.crt", []string{"token-*", "*.crt"}, true},
+		{"invalid pattern is skipped", "key", []string{"[", "key"}, true},
+		{"no patterns", "key", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAnyPattern(tt.key, tt.patterns); got != tt.want {
+				t.Errorf("MatchesAnyPattern(%q, %q) = %v, want %v", tt.key, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOnlyKeysAndIgnoreKeys checks that -only-keys selects keys first and
+// -ignore-keys then removes keys from that selection
+func TestOnlyKeysAndIgnoreKeys(t *testing.T) {
+	local := map[string]string{"db.user": "a", "db.pass": "b", "db.host": "c", "api.token": "d", "ca.crt": "e"}
+	deployed := map[string]string{"db.user": "x", "db.pass": "y", "db.host": "c", "api.token": "z", "ca.crt": "w"}
+	tests := []struct {
+		name     string
+		opts     Options
+		wantKeys []string
+	}{
+		{"neither", Options{}, []string{"api.token", "ca.crt", "db.pass", "db.user"}},
+		{"only keys", Options{OnlyKeys: []string{"db.*"}}, []string{"db.pass", "db.user"}},
+		{"only keys by exact name", Options{OnlyKeys: []string{"api.token", "ca.crt"}}, []string{"api.token", "ca.crt"}},
+		{"ignore keys", Options{IgnoreKeys: []string{"db.*"}}, []string{"api.token", "ca.crt"}},
+		{"ignore keys within only keys", Options{OnlyKeys: []string{"db.*"}, IgnoreKeys: []string{"db.pass"}}, []string{"db.user"}},
+		{"ignore keys outside only keys has no effect", Options{OnlyKeys: []string{"db.*"}, IgnoreKeys: []string{"ca.crt"}}, []string{"db.pass", "db.user"}},
+		{"ignore keys covering only keys leaves nothing", Options{OnlyKeys: []string{"db.*"}, IgnoreKeys: []string{"db.*"}}, nil},
+		{"only keys matching nothing", Options{OnlyKeys: []string{"missing"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			for _, diff := range Data(local, deployed, tt.opts) {
+				keys = append(keys, diff.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("differing keys = %q, want %q", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		key  string
+		opts Options
+		want bool
+	}{
+		{"any", Options{}, true},
+		{"ca.crt", Options{IgnoreKeys: []string{"ca.crt"}}, false},
+		{"tls.key", Options{IgnoreKeys: []string{"tls.*"}}, false},
+		{"token", Options{IgnoreKeys: []string{"tls.*"}}, true},
+		{"db.user", Options{OnlyKeys: []string{"db.*"}}, true},
+		{"api", Options{OnlyKeys: []string{"db.*"}}, false},
+		{"db.pass", Options{OnlyKeys: []string{"db.*"}, IgnoreKeys: []string{"db.pass"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Includes(tt.key); got != tt.want {
+			t.Errorf("Includes(%q) with %+v = %v, want %v", tt.key, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestGetLocalData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource LocalResource
+		want     map[string]string
+	}{
+		{
+			name: "secret data and stringData",
+			resource: &KubernetesSecret{
+				Data:       map[string]string{"user": "YWRtaW4=", "unpadded": "YWRtaW4"},
+				StringData: map[string]string{"pass": "secret"},
+			},
+			want: map[string]string{"user": "admin", "unpadded": "admin", "pass": "secret"},
+		},
+		{
+			name:     "secret with invalid base64 drops the key",
+			resource: &KubernetesSecret{Data: map[string]string{"bad": "!!!"}},
+			want:     map[string]string{},
+		},
+		{
+			name: "configmap data and binaryData",
+			resource: &KubernetesConfig{
+				Data:       map[string]string{"plain": "text"},
+				BinaryData: map[string]string{"bin": "AAEC"},
+			},
+			want: map[string]string{"plain": "text", "bin": "\x00\x01\x02"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resource.GetLocalData(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetLocalData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWhitespaceDigest checks that the streaming hash matches the hash of Canonical
+func TestWhitespaceDigest(t *testing.T) {
+	opts := Options{NormalizeWhitespace: true}
+	values := []string{"", "a", "a \r\nb\t\n", "a\r\r\n", "trailing\r", "\n\n", " \t \n x  "}
+	for _, value := range values {
+		if got, want := whitespaceDigest(value), sha256.Sum256([]byte(opts.Canonical(value))); got != want {
+			t.Errorf("whitespaceDigest(%q) does not match the hash of %q", value, opts.Canonical(value))
+		}
+	}
+}
+
+// largeValue returns a multi-line value of about size bytes, ending in suffix
+func largeValue(size int, suffix string) string {
+	line := strings.Repeat("x", 75) + "  \r\n"
+	return strings.Repeat(line, size/len(line)) + suffix
+}
+
+// BenchmarkData compares 4 MiB values that differ only in trailing whitespace
+// with -normalize-whitespace. The streaming hash avoids the canonical copies
+// made by comparing Canonical forms.
+func BenchmarkData(b *testing.B) {
+	local := map[string]string{"keystore": largeValue(4<<20, "end")}
+	deployed := map[string]string{"keystore": largeValue(4<<20, "end \t")}
+	opts := Options{NormalizeWhitespace: true}
+
+	b.Run("canonical-copies", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if opts.Canonical(local["keystore"]) != opts.Canonical(deployed["keystore"]) {
+				b.Fatal("values differ")
+			}
+		}
+	})
+	b.Run("streaming-hash", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if differences := Data(local, deployed, opts); len(differences) > 0 {
+				b.Fatal("values differ")
+			}
+		}
+	})
+}
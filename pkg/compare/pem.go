@@ -0,0 +1,35 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/pem"
+)
+
+// canonicalPEM re-encodes a value made only of PEM blocks, so blocks wrapped at
+// another width (e.g. 76 columns) compare equal to the standard 64. Values with
+// anything other than whitespace around the blocks are returned unchanged.
+func canonicalPEM(value string) string {
+	rest := []byte(value)
+	var out bytes.Buffer
+	for {
+		trimmed := bytes.TrimSpace(rest)
+		if len(trimmed) == 0 {
+			break
+		}
+		if !bytes.HasPrefix(trimmed, []byte("-----BEGIN ")) {
+			return value
+		}
+		block, remaining := pem.Decode(trimmed)
+		if block == nil {
+			return value
+		}
+		if err := pem.Encode(&out, block); err != nil {
+			return value
+		}
+		rest = remaining
+	}
+	if out.Len() == 0 {
+		return value
+	}
+	return out.String()
+}
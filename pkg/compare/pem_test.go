@@ -0,0 +1,80 @@
+package compare
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// wrapPEM encodes der as a PEM block of type typ with lines of width characters
+func wrapPEM(typ string, der []byte, width int) string {
+	encoded := base64.StdEncoding.EncodeToString(der)
+	var sb strings.Builder
+	sb.WriteString("-----BEGIN " + typ + "-----\n")
+	for len(encoded) > width {
+		sb.WriteString(encoded[:width] + "\n")
+		encoded = encoded[width:]
+	}
+	sb.WriteString(encoded + "\n-----END " + typ + "-----\n")
+	return sb.String()
+}
+
+// testDER returns n bytes standing in for a DER-encoded certificate
+func testDER(n int) []byte {
+	der := make([]byte, n)
+	for i := range der {
+		der[i] = byte(i * 7)
+	}
+	return der
+}
+
+func TestCanonicalPEM(t *testing.T) {
+	cert, key := testDER(300), testDER(120)
+	canonical := wrapPEM("CERTIFICATE", cert, 64)
+	chain := wrapPEM("CERTIFICATE", cert, 64) + wrapPEM("PRIVATE KEY", key, 64)
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"64 columns", wrapPEM("CERTIFICATE", cert, 64), canonical},
+		{"76 columns", wrapPEM("CERTIFICATE", cert, 76), canonical},
+		{"single line", wrapPEM("CERTIFICATE", cert, 10000), canonical},
+		{"surrounding whitespace", "\n  " + wrapPEM("CERTIFICATE", cert, 76) + "\n\n", canonical},
+		{"chain wrapped at 76 columns", wrapPEM("CERTIFICATE", cert, 76) + wrapPEM("PRIVATE KEY", key, 76), chain},
+		{"plain text", "not a certificate", "not a certificate"},
+		{"empty", "", ""},
+		{"text before the block", "subject: x\n" + wrapPEM("CERTIFICATE", cert, 76), "subject: x\n" + wrapPEM("CERTIFICATE", cert, 76)},
+		{"text after the block", wrapPEM("CERTIFICATE", cert, 76) + "trailer", wrapPEM("CERTIFICATE", cert, 76) + "trailer"},
+		{"truncated block", "-----BEGIN CERTIFICATE-----\nAAAA\n", "-----BEGIN CERTIFICATE-----\nAAAA\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalPEM(tt.value); got != tt.want {
+				t.Errorf("canonicalPEM() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDataNormalizePEM compares the same certificate wrapped at 64 and 76 columns
+func TestDataNormalizePEM(t *testing.T) {
+	cert := testDER(300)
+	local := map[string]string{"tls.crt": wrapPEM("CERTIFICATE", cert, 64), "note": "text"}
+	deployed := map[string]string{"tls.crt": wrapPEM("CERTIFICATE", cert, 76), "note": "text"}
+
+	if differences := Data(local, deployed, Options{NormalizePEM: true}); len(differences) > 0 {
+		t.Errorf("re-wrapped certificate differs with NormalizePEM: %s", describe(differences))
+	}
+	if differences := Data(local, deployed, Options{}); len(differences) != 1 || differences[0].Key != "tls.crt" {
+		t.Errorf("re-wrapped certificate without NormalizePEM: %s, want a difference in tls.crt", describe(differences))
+	}
+
+	deployed["tls.crt"] = wrapPEM("CERTIFICATE", testDER(301), 76)
+	if differences := Data(local, deployed, Options{NormalizePEM: true}); len(differences) != 1 {
+		t.Errorf("different certificate with NormalizePEM: %s, want a difference in tls.crt", describe(differences))
+	}
+	if got := Data(map[string]string{"v": "a  b"}, map[string]string{"v": "a b"}, Options{NormalizePEM: true}); len(got) != 1 {
+		t.Errorf("non-PEM values were normalized: %s", describe(got))
+	}
+}
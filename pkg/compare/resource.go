@@ -0,0 +1,206 @@
+package compare
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KubernetesSecret represents the structure of a Kubernetes Secret YAML file
+type KubernetesSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   Metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Immutable  *bool             `yaml:"immutable,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"` // base64-encoded
+}
+
+// KubernetesConfig represents the structure of a Kubernetes ConfigMap YAML file
+type KubernetesConfig struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   Metadata          `yaml:"metadata"`
+	Immutable  *bool             `yaml:"immutable,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	BinaryData map[string]string `yaml:"binaryData,omitempty"` // base64-encoded
+}
+
+// Metadata holds the metadata information for Kubernetes resources
+type Metadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace"`
+	Finalizers  []string          `yaml:"finalizers,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// LocalResource is an interface to unify local Secrets and ConfigMaps.
+type LocalResource interface {
+	GetName() string
+	GetNamespace() string
+	GetKind() string
+	GetLocalData() map[string]string
+	GetMergeField() string // "stringData" for Secrets; "data" for ConfigMaps.
+	GetFinalizers() []string
+	GetBinaryKeys() map[string]bool
+	GetType() string // Secret type; empty for ConfigMaps.
+	GetImmutable() *bool
+	GetLabels() map[string]string
+	GetAnnotations() map[string]string
+}
+
+// Implement LocalResource for KubernetesSecret.
+func (s *KubernetesSecret) GetName() string                   { return s.Metadata.Name }
+func (s *KubernetesSecret) GetNamespace() string              { return s.Metadata.Namespace }
+func (s *KubernetesSecret) GetKind() string                   { return s.Kind }
+func (s *KubernetesSecret) GetMergeField() string             { return "stringData" }
+func (s *KubernetesSecret) GetFinalizers() []string           { return s.Metadata.Finalizers }
+func (s *KubernetesSecret) GetBinaryKeys() map[string]bool    { return nil }
+func (s *KubernetesSecret) GetType() string                   { return s.Type }
+func (s *KubernetesSecret) GetLabels() map[string]string      { return s.Metadata.Labels }
+func (s *KubernetesSecret) GetAnnotations() map[string]string { return s.Metadata.Annotations }
+func (s *KubernetesSecret) GetImmutable() *bool               { return s.Immutable }
+
+// GetLocalData merges the decoded data field with stringData. As on the API
+// server, a key set in both takes its value from stringData.
+func (s *KubernetesSecret) GetLocalData() map[string]string {
+	// Values that fail to decode are dropped; callers validate them with DecodeData
+	decoded, _ := s.DecodeData()
+	merged := make(map[string]string, len(decoded)+len(s.StringData))
+	for key, value := range decoded {
+		merged[key] = value
+	}
+	// Written after data so stringData wins for overlapping keys
+	for key, value := range s.StringData {
+		merged[key] = value
+	}
+	return merged
+}
+
+// DecodeData base64-decodes the Secret's data field
+func (s *KubernetesSecret) DecodeData() (map[string]string, error) {
+	decoded := make(map[string]string, len(s.Data))
+	for key, value := range s.Data {
+		raw, err := decodeBase64(value)
+		if err != nil {
+			return decoded, fmt.Errorf("invalid base64 in data key '%s': %w", key, err)
+		}
+		decoded[key] = string(raw)
+	}
+	return decoded, nil
+}
+
+// OverlappingKeys returns the keys set in both data and stringData, sorted
+func (s *KubernetesSecret) OverlappingKeys() []string {
+	var keys []string
+	for key := range s.StringData {
+		if _, ok := s.Data[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NonCanonicalKeys returns the data keys, not overridden by stringData, whose
+// base64 differs from the canonical padded single-line encoding of their value
+func (s *KubernetesSecret) NonCanonicalKeys() []string {
+	var keys []string
+	for key, value := range s.Data {
+		if _, overridden := s.StringData[key]; overridden {
+			continue
+		}
+		raw, err := decodeBase64(value)
+		if err == nil && value != base64.StdEncoding.EncodeToString(raw) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UnpaddedKeys returns the data keys, sorted, whose base64 only decodes without
+// padding. They are compared, but the API server rejects such values.
+func (s *KubernetesSecret) UnpaddedKeys() []string { return unpaddedKeys(s.Data) }
+
+// unpaddedKeys returns the keys of data whose value decodes with decodeBase64
+// but not as standard padded base64, sorted
+func unpaddedKeys(data map[string]string) []string {
+	var keys []string
+	for key, value := range data {
+		if _, err := base64.StdEncoding.DecodeString(value); err == nil {
+			continue
+		}
+		if _, err := decodeBase64(value); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decodeBase64 decodes standard base64, also accepting values without padding.
+// Line breaks are ignored, as in the wrapped output of the base64 tool.
+func decodeBase64(value string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil && !strings.Contains(value, "=") {
+		if unpadded, rawErr := base64.RawStdEncoding.DecodeString(value); rawErr == nil {
+			return unpadded, nil
+		}
+	}
+	return raw, err
+}
+
+// Implement LocalResource for KubernetesConfig.
+func (c *KubernetesConfig) GetName() string                   { return c.Metadata.Name }
+func (c *KubernetesConfig) GetNamespace() string              { return c.Metadata.Namespace }
+func (c *KubernetesConfig) GetKind() string                   { return c.Kind }
+func (c *KubernetesConfig) GetMergeField() string             { return "data" }
+func (c *KubernetesConfig) GetFinalizers() []string           { return c.Metadata.Finalizers }
+func (c *KubernetesConfig) GetType() string                   { return "" }
+func (c *KubernetesConfig) GetLabels() map[string]string      { return c.Metadata.Labels }
+func (c *KubernetesConfig) GetAnnotations() map[string]string { return c.Metadata.Annotations }
+func (c *KubernetesConfig) GetImmutable() *bool               { return c.Immutable }
+
+// GetLocalData merges data with the decoded binaryData field
+func (c *KubernetesConfig) GetLocalData() map[string]string {
+	// Values that fail to decode are dropped; callers validate them with DecodeBinaryData
+	decoded, _ := c.DecodeBinaryData()
+	merged := make(map[string]string, len(c.Data)+len(decoded))
+	for key, value := range c.Data {
+		merged[key] = value
+	}
+	for key, value := range decoded {
+		merged[key] = value
+	}
+	return merged
+}
+
+// GetBinaryKeys returns the keys that come from binaryData
+func (c *KubernetesConfig) GetBinaryKeys() map[string]bool {
+	keys := make(map[string]bool, len(c.BinaryData))
+	for key := range c.BinaryData {
+		keys[key] = true
+	}
+	return keys
+}
+
+// UnpaddedKeys returns the binaryData keys, sorted, whose base64 only decodes
+// without padding. They are compared, but the API server rejects such values.
+func (c *KubernetesConfig) UnpaddedKeys() []string { return unpaddedKeys(c.BinaryData) }
+
+// DecodeBinaryData base64-decodes the ConfigMap's binaryData field
+func (c *KubernetesConfig) DecodeBinaryData() (map[string]string, error) {
+	decoded := make(map[string]string, len(c.BinaryData))
+	for key, value := range c.BinaryData {
+		raw, err := decodeBase64(value)
+		if err != nil {
+			return decoded, fmt.Errorf("invalid base64 in binaryData key '%s': %w", key, err)
+		}
+		decoded[key] = string(raw)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,127 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeBinaryData(t *testing.T) {
+	tests := []struct {
+		name       string
+		binaryData map[string]string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "padded and unpadded",
+			binaryData: map[string]string{"padded": "AAEC/w==", "unpadded": "AAEC/w"},
+			want:       map[string]string{"padded": "\x00\x01\x02\xff", "unpadded": "\x00\x01\x02\xff"},
+		},
+		{
+			name:       "wrapped lines",
+			binaryData: map[string]string{"wrapped": "AAEC\nAwQF"},
+			want:       map[string]string{"wrapped": "\x00\x01\x02\x03\x04\x05"},
+		},
+		{
+			name:       "invalid base64",
+			binaryData: map[string]string{"bad": "not base64!"},
+			want:       map[string]string{},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &KubernetesConfig{BinaryData: tt.binaryData}
+			got, err := config.DecodeBinaryData()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeBinaryData() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeBinaryData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBinaryKeys(t *testing.T) {
+	config := &KubernetesConfig{
+		Data:       map[string]string{"plain": "text"},
+		BinaryData: map[string]string{"bin": "AAEC", "other": "AwQ="},
+	}
+	want := map[string]bool{"bin": true, "other": true}
+	if got := config.GetBinaryKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBinaryKeys() = %v, want %v", got, want)
+	}
+	if got := (&KubernetesSecret{Data: map[string]string{"a": "AAEC"}}).GetBinaryKeys(); got != nil {
+		t.Errorf("Secret GetBinaryKeys() = %v, want nil", got)
+	}
+}
+
+// TestBinaryDataComparison compares decoded binaryData against deployed bytes
+func TestBinaryDataComparison(t *testing.T) {
+	config := &KubernetesConfig{BinaryData: map[string]string{"same": "AAEC", "changed": "AAEC"}}
+	deployed := map[string]string{"same": "\x00\x01\x02", "changed": "\x00\x01\x03"}
+	want := []SecretDifference{{Key: "changed", Local: strPtr("\x00\x01\x02"), Deployed: strPtr("\x00\x01\x03")}}
+	if got := Data(config.GetLocalData(), deployed, Options{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %s, want %s", describe(got), describe(want))
+	}
+}
+
+// TestNonCanonicalKeys encodes "admin" with and without padding and wrapped
+// over lines; all decode to the same value but only the padded form is canonical
+func TestNonCanonicalKeys(t *testing.T) {
+	secret := &KubernetesSecret{
+		Data: map[string]string{
+			"padded":     "YWRtaW4=",
+			"unpadded":   "YWRtaW4",
+			"wrapped":    "YWRt\naW4=",
+			"overridden": "YWRtaW4",
+		},
+		StringData: map[string]string{"overridden": "admin"},
+	}
+	want := []string{"unpadded", "wrapped"}
+	if got := secret.NonCanonicalKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NonCanonicalKeys() = %q, want %q", got, want)
+	}
+
+	deployed := map[string]string{"padded": "admin", "unpadded": "admin", "wrapped": "admin", "overridden": "admin"}
+	if differences := Data(secret.GetLocalData(), deployed, Options{}); len(differences) > 0 {
+		t.Errorf("differently padded encodings of the same value differ: %s", describe(differences))
+	}
+}
+
+// TestStringDataOverridesData checks that a key set in both data and stringData
+// takes its value from stringData, as on the API server
+func TestStringDataOverridesData(t *testing.T) {
+	secret := &KubernetesSecret{
+		Data:       map[string]string{"password": "b2xk", "user": "YWRtaW4=", "token": "dG9rZW4="},
+		StringData: map[string]string{"password": "new", "token": "override", "extra": "x"},
+	}
+	want := map[string]string{"password": "new", "user": "admin", "token": "override", "extra": "x"}
+	if got := secret.GetLocalData(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLocalData() = %q, want %q", got, want)
+	}
+	if got, want := secret.OverlappingKeys(), []string{"password", "token"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OverlappingKeys() = %q, want %q", got, want)
+	}
+	if got := (&KubernetesSecret{Data: map[string]string{"a": "YQ=="}, StringData: map[string]string{"b": "b"}}).OverlappingKeys(); got != nil {
+		t.Errorf("OverlappingKeys() without overlap = %q, want none", got)
+	}
+}
+
+func TestUnpaddedKeys(t *testing.T) {
+	secret := &KubernetesSecret{Data: map[string]string{
+		"padded":   "YWRtaW4=",
+		"unpadded": "YWRtaW4",
+		"aligned":  "YWRt",
+		"wrapped":  "YWRt\naW4=",
+		"invalid":  "!!!",
+	}}
+	if got, want := secret.UnpaddedKeys(), []string{"unpadded"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Secret UnpaddedKeys() = %q, want %q", got, want)
+	}
+	config := &KubernetesConfig{BinaryData: map[string]string{"bin": "AAE", "ok": "AAE="}}
+	if got, want := config.UnpaddedKeys(), []string{"bin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConfigMap UnpaddedKeys() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSemanticNotes caps the changed fields listed for a single key
+const maxSemanticNotes = 20
+
+// parseStructured parses a value as YAML (and therefore JSON). Only mappings
+// and sequences count as structured; plain scalars and values holding more than
+// one YAML document are compared as raw text.
+func parseStructured(value string) (interface{}, bool) {
+	decoder := yaml.NewDecoder(strings.NewReader(value))
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, false
+	}
+	var next interface{}
+	if err := decoder.Decode(&next); err != io.EOF {
+		return nil, false
+	}
+	switch parsed.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return parsed, true
+	default:
+		return nil, false
+	}
+}
+
+// compareStructured compares two values as parsed YAML/JSON documents. ok is
+// false when either side is not structured, so the raw comparison applies.
+// Otherwise changes lists the nested fields that differ, without their values.
+func compareStructured(local, deployed string) (changes []string, ok bool) {
+	localDoc, ok := parseStructured(local)
+	if !ok {
+		return nil, false
+	}
+	deployedDoc, ok := parseStructured(deployed)
+	if !ok {
+		return nil, false
+	}
+	diffStructures("", localDoc, deployedDoc, &changes)
+	if len(changes) > maxSemanticNotes {
+		changes = append(changes[:maxSemanticNotes], fmt.Sprintf("... and %d more changed fields", len(changes)-maxSemanticNotes))
+	}
+	return changes, true
+}
+
+// diffStructures appends a description of every difference between a and b below path
+func diffStructures(path string, a, b interface{}, changes *[]string) {
+	aMap, aIsMap := asMap(a)
+	bMap, bIsMap := asMap(b)
+	if aIsMap && bIsMap {
+		keys := make(map[string]struct{})
+		for key := range aMap {
+			keys[key] = struct{}{}
+		}
+		for key := range bMap {
+			keys[key] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			child := joinFieldPath(path, key)
+			aVal, inA := aMap[key]
+			bVal, inB := bMap[key]
+			switch {
+			case !inB:
+				*changes = append(*changes, fmt.Sprintf("field %s only in local", child))
+			case !inA:
+				*changes = append(*changes, fmt.Sprintf("field %s only in deployed", child))
+			default:
+				diffStructures(child, aVal, bVal, changes)
+			}
+		}
+		return
+	}
+
+	aList, aIsList := a.([]interface{})
+	bList, bIsList := b.([]interface{})
+	if aIsList && bIsList && len(aList) == len(bList) {
+		for i := range aList {
+			diffStructures(fmt.Sprintf("%s[%d]", path, i), aList[i], bList[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "(root)"
+		}
+		*changes = append(*changes, fmt.Sprintf("field %s changed", path))
+	}
+}
+
+// asMap returns a YAML mapping with its keys as strings
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			converted[fmt.Sprint(key)] = val
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// joinFieldPath appends a mapping key to a dotted field path
+func joinFieldPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		key = fmt.Sprintf("%q", key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,33 @@
+package compare
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatYAMLValue formats a value for a merge snippet, where it follows a key
+// indented by indent spaces. The value is encoded with yaml.v3 so the snippet parses
+// back to the original string: multi-line values use a literal block (|-) indented
+// one more level, others are double-quoted with escapes.
+func FormatYAMLValue(value string, indent int) string {
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+	if strings.Contains(value, "\n") && strings.TrimSpace(value) != "" {
+		// The encoder falls back to quoting when a literal block can't represent the value
+		scalar.Style = yaml.LiteralStyle
+	}
+	// Encode inside the same nesting as the snippet so block indentation is right
+	doc := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "data"},
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: "key"}, scalar}},
+	}}
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(indent)
+	if err := enc.Encode(doc); err != nil {
+		return strconv.Quote(value)
+	}
+	enc.Close()
+	return strings.TrimPrefix(strings.TrimSuffix(sb.String(), "\n"), "data:\n"+strings.Repeat(" ", indent)+"key: ")
+}
@@ -0,0 +1,89 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseSnippet parses a value formatted by FormatYAMLValue back from a snippet
+func parseSnippet(t *testing.T, formatted string, indent int) string {
+	t.Helper()
+	snippet := "stringData:\n" + strings.Repeat(" ", indent) + "key: " + formatted + "\n"
+	var parsed struct {
+		StringData map[string]string `yaml:"stringData"`
+	}
+	if err := yaml.Unmarshal([]byte(snippet), &parsed); err != nil {
+		t.Fatalf("snippet does not parse: %v\n%s", err, snippet)
+	}
+	return parsed.StringData["key"]
+}
+
+func TestFormatYAMLValue(t *testing.T) {
+	tests := []struct {
+		value  string
+		indent int
+		want   string
+	}{
+		{"plain", 2, `"plain"`},
+		{`say "hi"`, 2, `"say \"hi\""`},
+		{"", 2, `""`},
+		{"line1\nline2\n", 2, "|\n    line1\n    line2"},
+		{"line1\nline2", 4, "|-\n        line1\n        line2"},
+	}
+	for _, tt := range tests {
+		got := FormatYAMLValue(tt.value, tt.indent)
+		if got != tt.want {
+			t.Errorf("FormatYAMLValue(%q, %d) = %q, want %q", tt.value, tt.indent, got, tt.want)
+		}
+	}
+}
+
+// TestFormatYAMLValueRoundTrip checks that snippets parse back to the original value
+func TestFormatYAMLValueRoundTrip(t *testing.T) {
+	values := []string{"plain", "tab\there", "unicode ✓", "  leading spaces", "multi\nline\n", "trailing\n\n", "\n", "key: value", "# comment", "- item"}
+	for _, indent := range []int{2, 4, 8} {
+		for _, value := range values {
+			if got := parseSnippet(t, FormatYAMLValue(value, indent), indent); got != value {
+				t.Errorf("snippet for %q with indent %d parses to %q", value, indent, got)
+			}
+		}
+	}
+}
+
+func TestFormatYAMLValueSpecialCharacters(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"double quotes", `say "hi"`},
+		{"single quotes", `it's`},
+		{"both quotes and colon", `a: "b" 'c'`},
+		{"colon", "host:port"},
+		{"colon space", "key: value"},
+		{"leading folded indicator", "> folded"},
+		{"leading literal indicator", "| literal"},
+		{"leading dash", "- item"},
+		{"leading hash", "# comment"},
+		{"leading spaces", "   indented"},
+		{"trailing spaces", "value   "},
+		{"unicode", "naïve ✓ 日本語"},
+		{"emoji", "🔑"},
+		{"backslash", `C:\path\to`},
+		{"boolean-like", "yes"},
+		{"number-like", "0123"},
+		{"null-like", "~"},
+		{"multi-line with quotes", "line \"one\"\nline: two\n"},
+		{"multi-line with leading spaces", "  first\nsecond"},
+		{"control character", "bell\a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted := FormatYAMLValue(tt.value, 2)
+			if got := parseSnippet(t, formatted, 2); got != tt.value {
+				t.Errorf("FormatYAMLValue(%q) = %q, parses back to %q", tt.value, formatted, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+// preflightCheck verifies that the API server is reachable and that the current
+// identity may read every kind of resource to compare in each namespace, using
+// a SelfSubjectAccessReview per namespace, resource and verb. All missing
+// permissions are reported together.
+func preflightCheck(ctx context.Context, clientset kubernetes.Interface, resources []LocalResource, verbs []string, timeout time.Duration) error {
+	version, err := serverVersion(ctx, clientset, timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to the API server: %w", err)
+	}
+	debugf("Connected to Kubernetes %s", version.GitVersion)
+
+	type access struct{ namespace, resource string }
+	seen := make(map[access]bool)
+	var checks []access
+	for _, r := range resources {
+		a := access{namespace: r.GetNamespace(), resource: strings.ToLower(r.GetKind()) + "s"}
+		if !seen[a] {
+			seen[a] = true
+			checks = append(checks, a)
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].namespace != checks[j].namespace {
+			return checks[i].namespace < checks[j].namespace
+		}
+		return checks[i].resource < checks[j].resource
+	})
+
+	var missing []string
+	for _, a := range checks {
+		for _, verb := range verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{Namespace: a.namespace, Verb: verb, Resource: a.resource},
+				},
+			}
+			reviewCtx, cancel := context.WithTimeout(ctx, timeout)
+			result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(reviewCtx, review, metav1.CreateOptions{})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("error checking permission to %s %s in namespace '%s': %w", verb, a.resource, a.namespace, err)
+			}
+			if !result.Status.Allowed {
+				missing = append(missing, fmt.Sprintf("%s %s in namespace '%s'", verb, a.resource, a.namespace))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions: %s", strings.Join(missing, ", "))
+	}
+	debugf("Permission check passed for %d namespace and resource combinations", len(checks))
+	return nil
+}
+
+// serverVersion asks the API server for its version, giving up once timeout has
+// elapsed or ctx is cancelled. Discovery's ServerVersion takes no context, so
+// it would block on a hung API server.
+func serverVersion(ctx context.Context, clientset kubernetes.Interface, timeout time.Duration) (*version.Info, error) {
+	restClient := clientset.Discovery().RESTClient()
+	if restClient == nil || reflect.ValueOf(restClient).IsNil() {
+		// Fake clientsets have no REST client and answer immediately
+		return clientset.Discovery().ServerVersion()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	body, err := restClient.Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("no answer within %s: %w", timeout, ctx.Err())
+		}
+		return nil, err
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("error decoding the server version: %w", err)
+	}
+	return &info, nil
+}
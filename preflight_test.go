@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// TestPreflightCheckTimeout points the check at an API server that never
+// answers and checks it gives up once the timeout has elapsed
+func TestPreflightCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = preflightCheck(context.Background(), clientset, []LocalResource{testSecret("default", "app-secret")}, []string{"get"}, 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("check took %s with a 200ms timeout", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "error connecting to the API server") {
+		t.Errorf("error = %v, want one connecting to the API server", err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONReport is the machine-readable report emitted by -output json
+type JSONReport struct {
+	Resources []JSONResourceResult `json:"resources"`
+	Orphans   []string             `json:"orphans,omitempty"` // deployed resources without a local manifest
+	// UnmatchedDeployed are the deployed resources matching -selector without a local file
+	UnmatchedDeployed []unmatchedResource `json:"unmatchedDeployed,omitempty"`
+	Summary           JSONSummary         `json:"summary"`
+
+	// DriftedNamespaces is copied into the summary by Write
+	DriftedNamespaces []string `json:"-"`
+	// DifferencesFound is the run's verdict, which decides Summary.Match like the exit code
+	DifferencesFound bool `json:"-"`
+	// SkipManaged leaves the drift of operator-managed resources out of the summary (-skip-managed)
+	SkipManaged bool `json:"-"`
+}
+
+// JSONResourceResult holds the comparison result for a single resource
+type JSONResourceResult struct {
+	Kind                     string           `json:"kind"`
+	Name                     string           `json:"name"`
+	Namespace                string           `json:"namespace"`
+	NotDeployed              bool             `json:"notDeployed,omitempty"`
+	Managed                  bool             `json:"managed,omitempty"` // the deployed resource looks operator-managed
+	ResourceVersion          string           `json:"resourceVersion,omitempty"`
+	MatchingKeys             int              `json:"matchingKeys"`
+	Differences              []JSONDifference `json:"differences"`
+	FinalizersOnlyInLocal    []string         `json:"finalizersOnlyInLocal,omitempty"`
+	FinalizersOnlyInDeployed []string         `json:"finalizersOnlyInDeployed,omitempty"`
+	FieldDifferences         []JSONField      `json:"fieldDifferences,omitempty"`
+	LabelDifferences         []JSONDifference `json:"labelDifferences,omitempty"`
+	AnnotationDifferences    []JSONDifference `json:"annotationDifferences,omitempty"`
+}
+
+// JSONField is a mismatch in a resource field other than a data key, e.g. the Secret type
+type JSONField struct {
+	Field    string `json:"field"`
+	Local    string `json:"local"`
+	Deployed string `json:"deployed"`
+}
+
+// JSONDifference is a single differing key
+type JSONDifference struct {
+	Key      string   `json:"key"`
+	Local    *string  `json:"local"`
+	Deployed *string  `json:"deployed"`
+	Status   string   `json:"status"` // DIFFERENT, ONLY_IN_LOCAL or ONLY_IN_DEPLOYED
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// JSONSummary aggregates the results of a run
+type JSONSummary struct {
+	Resources                int      `json:"resources"`
+	ResourcesWithDifferences int      `json:"resourcesWithDifferences"`
+	Differences              int      `json:"differences"`
+	Orphans                  int      `json:"orphans,omitempty"`
+	UnmatchedDeployed        int      `json:"unmatchedDeployed,omitempty"`
+	DriftedNamespaces        []string `json:"driftedNamespaces,omitempty"` // the -namespaces with differences
+	Match                    bool     `json:"match"`
+}
+
+// AddResult records the result for a resource.
+// When the result is masked, values are replaced by a length and hash summary.
+func (r *JSONReport) AddResult(res ComparisonResult) {
+	result := JSONResourceResult{
+		Kind:                     res.Kind,
+		Name:                     res.Name,
+		Namespace:                res.Namespace,
+		NotDeployed:              res.NotDeployed,
+		Managed:                  res.Managed,
+		ResourceVersion:          res.ResourceVersion,
+		MatchingKeys:             res.MatchingKeys,
+		Differences:              []JSONDifference{},
+		FinalizersOnlyInLocal:    res.FinalizersOnlyInLocal,
+		FinalizersOnlyInDeployed: res.FinalizersOnlyInDeployed,
+	}
+	for _, diff := range res.Differences {
+		local, deployed := diff.Local, diff.Deployed
+		if diff.Binary {
+			local, deployed = summarizeOptional(local, binarySummary), summarizeOptional(deployed, binarySummary)
+		} else if res.Mask {
+			local, deployed = summarizeOptional(local, maskValue), summarizeOptional(deployed, maskValue)
+		}
+		result.Differences = append(result.Differences, JSONDifference{
+			Key:      diff.Key,
+			Local:    local,
+			Deployed: deployed,
+			Status:   diffStatus(diff),
+			Notes:    diff.Notes,
+		})
+	}
+	for _, field := range res.FieldDifferences {
+		result.FieldDifferences = append(result.FieldDifferences, JSONField(field))
+	}
+	for _, diff := range res.LabelDifferences {
+		result.LabelDifferences = append(result.LabelDifferences, JSONDifference{Key: diff.Key, Local: diff.Local, Deployed: diff.Deployed, Status: diffStatus(diff)})
+	}
+	for _, diff := range res.AnnotationDifferences {
+		result.AnnotationDifferences = append(result.AnnotationDifferences, JSONDifference{Key: diff.Key, Local: diff.Local, Deployed: diff.Deployed, Status: diffStatus(diff)})
+	}
+	r.Resources = append(r.Resources, result)
+}
+
+// Write fills in the summary and writes the report as indented JSON
+func (r *JSONReport) Write(w io.Writer) error {
+	r.summarize()
+	if r.Resources == nil {
+		r.Resources = []JSONResourceResult{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// summarize computes the summary from the recorded results
+func (r *JSONReport) summarize() {
+	r.Summary = JSONSummary{Resources: len(r.Resources), Match: !r.DifferencesFound}
+	for _, res := range r.Resources {
+		if res.Managed && r.SkipManaged {
+			continue
+		}
+		count := len(res.Differences) + len(res.FinalizersOnlyInLocal) + len(res.FinalizersOnlyInDeployed) +
+			len(res.FieldDifferences) + len(res.LabelDifferences) + len(res.AnnotationDifferences)
+		if res.NotDeployed {
+			count++
+		}
+		if count > 0 {
+			r.Summary.ResourcesWithDifferences++
+		}
+		r.Summary.Differences += count
+	}
+	r.Summary.DriftedNamespaces = r.DriftedNamespaces
+	r.Summary.Orphans = len(r.Orphans)
+	r.Summary.UnmatchedDeployed = len(r.UnmatchedDeployed)
+}
+
+// JSONRunSummary is the compact object written by -output json-summary.
+// It holds counts only, never keys or values.
+type JSONRunSummary struct {
+	Checked       int  `json:"checked"`
+	Drifted       int  `json:"drifted"`
+	Missing       int  `json:"missing"`
+	Errors        int  `json:"errors"`
+	Orphans       int  `json:"orphans"`
+	DifferingKeys int  `json:"differingKeys"`
+	Match         bool `json:"match"`
+}
+
+// summarizeOptional applies summarize to an optional value
+func summarizeOptional(value *string, summarize func(string) string) *string {
+	if value == nil {
+		return nil
+	}
+	summarized := summarize(*value)
+	return &summarized
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnitTestSuite is the root element emitted by -output junit
+type JUnitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is the result for a single compared resource
+type JUnitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+}
+
+// JUnitFailure describes the drift found for a resource
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// NewJUnitReport returns an empty test suite
+func NewJUnitReport() *JUnitTestSuite {
+	return &JUnitTestSuite{Name: "k8s-secret-compare"}
+}
+
+// AddResult records a test case for a resource, failing it when drift was found
+func (s *JUnitTestSuite) AddResult(result ComparisonResult) {
+	testCase := JUnitTestCase{
+		ClassName: result.Namespace,
+		Name:      result.Kind + "/" + result.Name,
+	}
+	details := describeResult(result)
+	if len(details) > 0 {
+		testCase.Failure = &JUnitFailure{
+			Message: fmt.Sprintf("%d differences found", len(details)),
+			Type:    "drift",
+			Body:    strings.Join(details, "\n"),
+		}
+		s.Failures++
+	}
+	s.Tests++
+	s.TestCases = append(s.TestCases, testCase)
+}
+
+// AddUnmatched records a failing test case for a deployed resource matching
+// -selector without a local file
+func (s *JUnitTestSuite) AddUnmatched(resource unmatchedResource) {
+	s.TestCases = append(s.TestCases, JUnitTestCase{
+		ClassName: resource.Namespace,
+		Name:      resource.Kind + "/" + resource.Name,
+		Failure: &JUnitFailure{
+			Message: "no local file",
+			Type:    "drift",
+			Body:    fmt.Sprintf("[ONLY IN DEPLOYED] the %s matches -selector but has no local file", strings.ToLower(resource.Kind)),
+		},
+	})
+	s.Tests++
+	s.Failures++
+}
+
+// Write writes the test suite as indented XML
+func (s *JUnitTestSuite) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// describeResult renders every difference in a result as a line of text
+func describeResult(result ComparisonResult) []string {
+	lines := describeDifferences(result.Differences, result.Mask)
+	if result.NotDeployed {
+		lines = append(lines, fmt.Sprintf("[NOT DEPLOYED] the %s does not exist in the cluster", strings.ToLower(result.Kind)))
+	}
+	for _, f := range result.FinalizersOnlyInLocal {
+		lines = append(lines, fmt.Sprintf("[ONLY IN LOCAL] finalizer: %s", f))
+	}
+	for _, f := range result.FinalizersOnlyInDeployed {
+		lines = append(lines, fmt.Sprintf("[ONLY IN DEPLOYED] finalizer: %s", f))
+	}
+	for _, field := range result.FieldDifferences {
+		lines = append(lines, fmt.Sprintf("[DIFFERENT] %s: local=%s deployed=%s", field.Field, field.Local, field.Deployed))
+	}
+	for _, line := range describeDifferences(result.LabelDifferences, false) {
+		lines = append(lines, "label "+line)
+	}
+	for _, line := range describeDifferences(result.AnnotationDifferences, false) {
+		lines = append(lines, "annotation "+line)
+	}
+	return lines
+}
+
+// describeDifferences renders each difference as a single line of text,
+// masking or summarizing values the same way as the text report
+func describeDifferences(differences []SecretDifference, mask bool) []string {
+	display := func(diff SecretDifference, value string) string {
+		if diff.Binary {
+			return binarySummary(value)
+		}
+		if mask {
+			return maskValue(value)
+		}
+		return value
+	}
+
+	var lines []string
+	for _, diff := range differences {
+		switch {
+		case diff.Local != nil && diff.Deployed != nil:
+			lines = append(lines, fmt.Sprintf("[DIFFERENT] %s: local=%s deployed=%s", diff.Key, display(diff, *diff.Local), display(diff, *diff.Deployed)))
+		case diff.Local != nil:
+			lines = append(lines, fmt.Sprintf("[ONLY IN LOCAL] %s: %s", diff.Key, display(diff, *diff.Local)))
+		default:
+			lines = append(lines, fmt.Sprintf("[ONLY IN DEPLOYED] %s: %s", diff.Key, display(diff, *diff.Deployed)))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MarkdownReport renders the results as a Markdown document for pull request comments
+type MarkdownReport struct {
+	Results       []ComparisonResult
+	SnippetIndent int // spaces per indentation level in merge snippets
+	// UnmatchedDeployed are the deployed resources matching -selector without a local file
+	UnmatchedDeployed []unmatchedResource
+}
+
+// AddResult records the result for a resource
+func (r *MarkdownReport) AddResult(result ComparisonResult) {
+	r.Results = append(r.Results, result)
+}
+
+// Write writes a summary table followed by a collapsible section per drifted resource.
+// Masked values stay masked and their merge snippets are left out.
+func (r *MarkdownReport) Write(w io.Writer) error {
+	var sb strings.Builder
+	drifted := len(r.UnmatchedDeployed)
+	for _, result := range r.Results {
+		if result.HasDifferences() {
+			drifted++
+		}
+	}
+	total := len(r.Results) + len(r.UnmatchedDeployed)
+
+	sb.WriteString("## Secret & ConfigMap drift\n\n")
+	if drifted == 0 {
+		fmt.Fprintf(&sb, "All %d resources match the cluster.\n\n", total)
+	} else {
+		fmt.Fprintf(&sb, "%d of %d resources differ from the cluster.\n\n", drifted, total)
+	}
+
+	if total > 0 {
+		sb.WriteString("| Kind | Namespace | Name | Status |\n|---|---|---|---|\n")
+		for _, result := range r.Results {
+			status := "✅ Match"
+			if result.HasDifferences() {
+				status = fmt.Sprintf("❌ %d differences", len(describeResult(result)))
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", result.Kind, markdownCell(result.Namespace), markdownCell(result.Name), status)
+		}
+		for _, resource := range r.UnmatchedDeployed {
+			fmt.Fprintf(&sb, "| %s | %s | %s | ❌ No local file |\n", resource.Kind, markdownCell(resource.Namespace), markdownCell(resource.Name))
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, result := range r.Results {
+		if !result.HasDifferences() {
+			continue
+		}
+		fmt.Fprintf(&sb, "<details>\n<summary>%s <code>%s/%s</code></summary>\n\n", result.Kind, result.Namespace, result.Name)
+		sb.WriteString(markdownFence("", strings.Join(describeResult(result), "\n")))
+		if patch := renderPatch(result, r.SnippetIndent); patch != "" {
+			if result.Mask {
+				sb.WriteString("Merge snippet hidden while values are masked.\n\n")
+			} else {
+				sb.WriteString("Merge into the local file to match the cluster:\n\n")
+				sb.WriteString(markdownFence("yaml", patch))
+			}
+		}
+		sb.WriteString("</details>\n\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownFence wraps content in a fenced code block, using a fence longer
+// than any run of backticks in the content so values cannot break out of it
+func markdownFence(lang, content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("%s%s\n%s\n%s\n\n", fence, lang, strings.TrimSuffix(content, "\n"), fence)
+}
+
+// markdownCell escapes a value for use in a table cell
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
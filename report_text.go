@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+// textReporter prints the human-readable report as results come in
+type textReporter struct {
+	w            io.Writer
+	opts         TextOptions
+	quiet        bool // print only the summary line
+	printKubectl bool
+}
+
+func (r *textReporter) AddResult(result ComparisonResult) {
+	if r.quiet {
+		return
+	}
+	r.printResult(result)
+	if r.printKubectl {
+		r.printKubectlPatch(result)
+	}
+}
+
+func (r *textReporter) Finish(summary RunSummary) error {
+	if !r.quiet && len(summary.UnmatchedDeployed) > 0 {
+		r.printUnmatchedDeployed(summary.UnmatchedDeployed)
+	}
+	if !r.quiet && len(summary.Orphans) > 0 {
+		r.printOrphans(summary.Orphans)
+	}
+	if summary.Interrupted > 0 {
+		fmt.Fprintf(r.w, "Summary: Interrupted; %d resources were not checked.\n", summary.Interrupted)
+	} else if summary.DifferencesFound {
+		fmt.Fprintln(r.w, "Summary: Differences were found in some resources.")
+	} else {
+		fmt.Fprintln(r.w, "Summary: All secrets match across environments.")
+	}
+	if !r.quiet {
+		summary.Stats.Print(r.w)
+	}
+	return nil
+}
+
+// printResult prints the text report for a single resource
+func (r *textReporter) printResult(result ComparisonResult) {
+	if r.opts.OnlyDiff && !result.HasDifferences() {
+		return
+	}
+	if result.NotDeployed {
+		fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\n - %s The %s does not exist in the cluster.\n\n", result.Name, result.Namespace, colorize(colorRed, "[NOT DEPLOYED]"), strings.ToLower(result.Kind))
+		return
+	}
+	// "All ... match" only when nothing else differs either: equal data with a
+	// different type, finalizer or annotation is still a difference
+	if len(result.Differences) > 0 || !result.HasDifferences() {
+		r.printDifferences(result.Kind, result.Name, result.Namespace, result.Differences, result.MatchingKeys, result.MergeField, result.Mask)
+	}
+	if result.DumpDir != "" {
+		fmt.Fprintf(r.w, "Differing values written to %s (local/<key> and deployed/<key>)\n\n", result.DumpDir)
+	}
+	r.printMatchingValues(result.Matching)
+	r.printFinalizerDifferences(result.Kind, result.Name, result.Namespace, result.FinalizersOnlyInLocal, result.FinalizersOnlyInDeployed)
+	r.printFieldDifferences(result.Kind, result.Name, result.Namespace, result.FieldDifferences)
+	r.printMetadataDifferences(result.Kind, result.Name, result.Namespace, result.LabelDifferences, result.AnnotationDifferences)
+}
+
+// printMetadataDifferences prints label and annotation differences
+func (r *textReporter) printMetadataDifferences(kind, name, namespace string, labels, annotations []SecretDifference) {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return
+	}
+	fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nMetadata differences found for %s:\n", name, namespace, kind)
+	printEntry := func(field string, diff SecretDifference) {
+		switch {
+		case diff.Local != nil && diff.Deployed != nil:
+			fmt.Fprintf(r.w, " - %s %s %s:\n", colorize(colorYellow, "[DIFFERENT]"), field, diff.Key)
+			fmt.Fprintf(r.w, "   Local:     %s\n", *diff.Local)
+			fmt.Fprintf(r.w, "   Deployed:  %s\n\n", *diff.Deployed)
+		case diff.Local != nil:
+			fmt.Fprintf(r.w, " - %s %s %s:\n", colorize(colorGreen, "[ONLY IN LOCAL]"), field, diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n\n", *diff.Local)
+		default:
+			fmt.Fprintf(r.w, " - %s %s %s:\n", colorize(colorRed, "[ONLY IN DEPLOYED]"), field, diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n\n", *diff.Deployed)
+		}
+	}
+	for _, diff := range labels {
+		printEntry("label", diff)
+	}
+	for _, diff := range annotations {
+		printEntry("annotation", diff)
+	}
+}
+
+// printFieldDifferences prints mismatches in fields other than data keys
+func (r *textReporter) printFieldDifferences(kind, name, namespace string, differences []FieldDifference) {
+	if len(differences) == 0 {
+		return
+	}
+	fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nField differences found for %s:\n", name, namespace, kind)
+	for _, diff := range differences {
+		fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorYellow, "[DIFFERENT]"), diff.Field)
+		fmt.Fprintf(r.w, "   Local:     %s\n", diff.Local)
+		fmt.Fprintf(r.w, "   Deployed:  %s\n\n", diff.Deployed)
+		if diff.Field == "immutable" && diff.Deployed == "true" {
+			fmt.Fprintf(r.w, "   The deployed %s is immutable; it must be deleted and recreated to change it.\n\n", strings.ToLower(kind))
+		}
+	}
+}
+
+// printFinalizerDifferences prints finalizers that differ between the local
+// and deployed resource. Lingering finalizers are a common cause of stuck deletions.
+func (r *textReporter) printFinalizerDifferences(kind, name, namespace string, onlyLocal, onlyDeployed []string) {
+	if len(onlyLocal) == 0 && len(onlyDeployed) == 0 {
+		return
+	}
+	fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nFinalizer differences found for %s:\n", name, namespace, kind)
+	for _, f := range onlyLocal {
+		fmt.Fprintf(r.w, " - %s finalizer: %s\n", colorize(colorGreen, "[ONLY IN LOCAL]"), f)
+	}
+	for _, f := range onlyDeployed {
+		fmt.Fprintf(r.w, " - %s finalizer: %s\n", colorize(colorRed, "[ONLY IN DEPLOYED]"), f)
+	}
+	fmt.Fprintln(r.w)
+}
+
+// TextOptions controls how the text report is rendered
+type TextOptions struct {
+	Unified        bool // render changed values as a unified diff
+	LocalToCluster bool // frame changes as what applying the local files would do to the cluster
+	OnlyDiff       bool // skip resources without differences
+	ShowMatching   bool // list the length and hash of every matching value
+	MaxValuePrint  int  // values longer than this many bytes are summarized; 0 prints everything
+	DiffContext    int  // unchanged lines shown around each change in unified diffs
+	SnippetIndent  int  // spaces per indentation level in merge snippets
+}
+
+// keyCounts describes the number of differing and matching keys of a resource,
+// e.g. "3 differences, 12 keys match"
+func keyCounts(differences, matching int) string {
+	diffWord, matchWord := "differences", "keys match"
+	if differences == 1 {
+		diffWord = "difference"
+	}
+	if matching == 1 {
+		matchWord = "key matches"
+	}
+	return fmt.Sprintf("%d %s, %d %s", differences, diffWord, matching, matchWord)
+}
+
+// tooLarge reports whether a value is over the MaxValuePrint threshold
+func (o TextOptions) tooLarge(value string) bool {
+	return o.MaxValuePrint > 0 && len(value) > o.MaxValuePrint
+}
+
+// printDifferences prints the comparison results and outputs YAML snippets
+// for key-value pairs that should be merged locally.
+// When mask is set, values are replaced by a length and hash summary and the snippets are omitted.
+func (r *textReporter) printDifferences(kind, name, namespace string, differences []SecretDifference, matching int, mergeField string, mask bool) {
+	display := func(diff SecretDifference, value string) string {
+		if diff.Binary {
+			return colorize(colorYellow, "[BINARY]") + " " + binarySummary(value)
+		}
+		if mask {
+			return maskValue(value)
+		}
+		if r.opts.tooLarge(value) {
+			return colorize(colorYellow, "[LARGE]") + " " + largeSummary(value)
+		}
+		return value
+	}
+
+	if len(differences) == 0 {
+		fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nAll %s match between the local file and the deployed Kubernetes %s.\n\n", name, namespace, kind, kind)
+		return
+	}
+	if r.opts.LocalToCluster {
+		r.printClusterChanges(kind, name, namespace, differences, matching, mergeField, mask, display)
+		return
+	}
+	fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nDifferences found (%s):\n", name, namespace, keyCounts(len(differences), matching))
+
+	missingLocalKeys := make(map[string]string)
+	replaceLocalKeys := make(map[string]string)
+
+	for _, diff := range differences {
+		switch {
+		case diff.Local != nil && diff.Deployed != nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorYellow, "[DIFFERENT]"), diff.Key)
+			if r.opts.Unified && !mask && !diff.Binary && !r.opts.tooLarge(*diff.Local) && !r.opts.tooLarge(*diff.Deployed) {
+				fmt.Fprintln(r.w, unifiedDiff("local/"+diff.Key, "deployed/"+diff.Key, *diff.Local, *diff.Deployed, r.opts.DiffContext))
+			} else {
+				fmt.Fprintf(r.w, "   Local:     %s\n", display(diff, *diff.Local))
+				fmt.Fprintf(r.w, "   Deployed:  %s\n\n", display(diff, *diff.Deployed))
+			}
+			r.printNotes(diff.Notes)
+			if !diff.Binary {
+				replaceLocalKeys[diff.Key] = *diff.Deployed
+			}
+		case diff.Local != nil && diff.Deployed == nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorGreen, "[ONLY IN LOCAL]"), diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n\n", display(diff, *diff.Local))
+		case diff.Local == nil && diff.Deployed != nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorRed, "[ONLY IN DEPLOYED]"), diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n\n", display(diff, *diff.Deployed))
+			if !diff.Binary {
+				missingLocalKeys[diff.Key] = *diff.Deployed
+			}
+		}
+	}
+
+	if mask && (len(replaceLocalKeys) > 0 || len(missingLocalKeys) > 0) {
+		fmt.Fprintln(r.w, "Merge snippets are hidden while values are masked (use -mask=false to show them).")
+		fmt.Fprintln(r.w)
+		return
+	}
+
+	// the new locals doenst need a copy snippet as is can de applied as it is
+	if len(replaceLocalKeys) > 0 {
+		r.printSnippet(fmt.Sprintf("Merge the following key-value pairs into your local file to match deployed %s:", strings.ToLower(kind)), mergeField, replaceLocalKeys)
+	}
+	if len(missingLocalKeys) > 0 {
+		r.printSnippet(fmt.Sprintf("Add the following key-value pairs locally to match the deployed %s:", strings.ToLower(kind)), mergeField, missingLocalKeys)
+	}
+}
+
+// printClusterChanges prints differences framed as the changes applying the
+// local file would make to the cluster. The comparison itself is unchanged.
+func (r *textReporter) printClusterChanges(kind, name, namespace string, differences []SecretDifference, matching int, mergeField string, mask bool, display func(SecretDifference, string) string) {
+	fmt.Fprintf(r.w, "=== %s (Namespace: %s) ===\nApplying the local file would change the deployed %s (%s):\n", name, namespace, strings.ToLower(kind), keyCounts(len(differences), matching))
+
+	clusterKeys := make(map[string]string)
+	for _, diff := range differences {
+		switch {
+		case diff.Local != nil && diff.Deployed != nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorYellow, "[WILL BE UPDATED]"), diff.Key)
+			if r.opts.Unified && !mask && !diff.Binary && !r.opts.tooLarge(*diff.Local) && !r.opts.tooLarge(*diff.Deployed) {
+				fmt.Fprintln(r.w, unifiedDiff("deployed/"+diff.Key, "local/"+diff.Key, *diff.Deployed, *diff.Local, r.opts.DiffContext))
+			} else {
+				fmt.Fprintf(r.w, "   Current:   %s\n", display(diff, *diff.Deployed))
+				fmt.Fprintf(r.w, "   New:       %s\n\n", display(diff, *diff.Local))
+			}
+			r.printNotes(diff.Notes)
+			if !diff.Binary {
+				clusterKeys[diff.Key] = *diff.Local
+			}
+		case diff.Local != nil && diff.Deployed == nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorGreen, "[WILL BE ADDED TO CLUSTER]"), diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n\n", display(diff, *diff.Local))
+			if !diff.Binary {
+				clusterKeys[diff.Key] = *diff.Local
+			}
+		case diff.Local == nil && diff.Deployed != nil:
+			fmt.Fprintf(r.w, " - %s %s:\n", colorize(colorRed, "[WILL REMAIN OR BE REMOVED]"), diff.Key)
+			fmt.Fprintf(r.w, "   Value: %s\n", display(diff, *diff.Deployed))
+			fmt.Fprintf(r.w, "   Kept by a merge, removed by kubectl apply if it was applied from a previous local file\n\n")
+		}
+	}
+
+	if len(clusterKeys) == 0 {
+		return
+	}
+	if mask {
+		fmt.Fprintln(r.w, "Snippets are hidden while values are masked (use -mask=false to show them).")
+		fmt.Fprintln(r.w)
+		return
+	}
+	r.printSnippet(fmt.Sprintf("The following key-value pairs will be written to the deployed %s:", strings.ToLower(kind)), mergeField, clusterKeys)
+}
+
+// printNotes prints the human-readable details attached to a difference
+func (r *textReporter) printNotes(notes []string) {
+	for _, note := range notes {
+		fmt.Fprintf(r.w, "   Note:      %s\n", note)
+	}
+	if len(notes) > 0 {
+		fmt.Fprintln(r.w)
+	}
+}
+
+// printMatchingValues lists matching keys with the length and hash of their value,
+// so the compared values can be confirmed without printing them
+func (r *textReporter) printMatchingValues(matching map[string]string) {
+	if len(matching) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(matching))
+	for key := range matching {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(r.w, "Matching keys:")
+	for _, key := range keys {
+		fmt.Fprintf(r.w, " - %s: %s\n", key, maskValue(matching[key]))
+	}
+	fmt.Fprintln(r.w)
+}
+
+// printUnmatchedDeployed lists deployed resources found by -selector that have no local file
+func (r *textReporter) printUnmatchedDeployed(unmatched []unmatchedResource) {
+	fmt.Fprintln(r.w, "=== Deployed resources without a local file ===")
+	for _, resource := range unmatched {
+		fmt.Fprintf(r.w, " - %s %s\n", colorize(colorRed, "[ONLY IN DEPLOYED]"), resource)
+	}
+	fmt.Fprintln(r.w)
+}
+
+// printOrphans lists deployed resources found by -detect-orphans that have no local manifest
+func (r *textReporter) printOrphans(descriptions []string) {
+	fmt.Fprintln(r.w, "=== Orphaned deployed resources (no local manifest) ===")
+	for _, description := range descriptions {
+		fmt.Fprintf(r.w, " - %s %s\n", colorize(colorRed, "[ORPHAN]"), description)
+	}
+	fmt.Fprintln(r.w)
+}
+
+// printKubectlPatch prints the kubectl command that pushes local values to the cluster
+func (r *textReporter) printKubectlPatch(result ComparisonResult) {
+	command := renderKubectlPatch(result)
+	if command == "" {
+		return
+	}
+	if result.Mask {
+		fmt.Fprintln(r.w, "The kubectl patch command is hidden while values are masked (use -mask=false to show it).")
+		fmt.Fprintln(r.w)
+		return
+	}
+	fmt.Fprintln(r.w, "Run the following command to push the local values to the cluster:")
+	fmt.Fprintln(r.w, colorize(colorDim, command))
+	fmt.Fprintln(r.w)
+}
+
+// printSnippet prints a fenced YAML snippet setting values under mergeField.
+// Values over the MaxValuePrint threshold are replaced by a YAML comment.
+func (r *textReporter) printSnippet(intro, mergeField string, values map[string]string) {
+	pad := strings.Repeat(" ", r.opts.SnippetIndent)
+	fmt.Fprintln(r.w, intro)
+	fmt.Fprintln(r.w, colorize(colorDim, "```yaml"))
+	fmt.Fprintln(r.w, colorize(colorDim, mergeField+":"))
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		if r.opts.tooLarge(value) {
+			fmt.Fprintln(r.w, colorize(colorDim, fmt.Sprintf("%s# %s: %d bytes left out (use -write-patch or raise -max-value-print)", pad, key, len(value))))
+			continue
+		}
+		fmt.Fprintln(r.w, colorize(colorDim, fmt.Sprintf("%s%s: %s", pad, key, compare.FormatYAMLValue(value, r.opts.SnippetIndent))))
+	}
+	fmt.Fprintln(r.w, colorize(colorDim, "```"))
+	fmt.Fprintln(r.w)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+// TestTextReportBinary checks that binary values are summarized and left out of
+// the merge snippet, while text values are printed
+func TestTextReportBinary(t *testing.T) {
+	binaryLocal, binaryDeployed := "PK\x00\x03local", "PK\x00\x03deployed"
+	textLocal, textDeployed := "local", "deployed"
+	result := ComparisonResult{
+		Kind:       "Secret",
+		Name:       "keystore",
+		Namespace:  "default",
+		MergeField: "stringData",
+		Differences: []SecretDifference{
+			{Key: "keystore.jks", Local: &binaryLocal, Deployed: &binaryDeployed, Binary: true},
+			{Key: "password", Local: &textLocal, Deployed: &textDeployed},
+		},
+	}
+	var out bytes.Buffer
+	reporter := &textReporter{w: &out, opts: TextOptions{SnippetIndent: 2}}
+	reporter.AddResult(result)
+	report := out.String()
+
+	for _, want := range []string{
+		"[BINARY] " + binarySummary(binaryLocal),
+		"[BINARY] " + binarySummary(binaryDeployed),
+		"Local:     local",
+		"Deployed:  deployed",
+		`  password: "deployed"`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "\x00") {
+		t.Errorf("report contains raw binary bytes:\n%q", report)
+	}
+	if strings.Contains(report, "  keystore.jks: ") {
+		t.Errorf("snippet contains the binary key:\n%s", report)
+	}
+}
+
+// TestTextReportStableOrder checks that the text report, including the merge
+// snippet, is identical between runs and lists keys in sorted order
+func TestTextReportStableOrder(t *testing.T) {
+	local := map[string]string{"zeta": "1", "alpha": "1", "mid": "1", "only-local": "x"}
+	deployed := map[string]string{"zeta": "2", "alpha": "2", "mid": "2", "only-deployed": "y", "beta": "z"}
+	render := func() string {
+		result := ComparisonResult{
+			Kind:        "Secret",
+			Name:        "app",
+			Namespace:   "default",
+			MergeField:  "stringData",
+			Differences: compare.Data(local, deployed, CompareOptions{}),
+		}
+		var out bytes.Buffer
+		(&textReporter{w: &out, opts: TextOptions{SnippetIndent: 2}}).AddResult(result)
+		return out.String()
+	}
+
+	first := render()
+	for i := 0; i < 20; i++ {
+		if got := render(); got != first {
+			t.Fatalf("report changed between runs:\n%s\n---\n%s", first, got)
+		}
+	}
+	var listed []string
+	for _, line := range strings.Split(first, "\n") {
+		if strings.HasPrefix(line, " - [") {
+			listed = append(listed, strings.TrimSuffix(line[strings.Index(line, "] ")+2:], ":"))
+		}
+	}
+	if want := []string{"alpha", "beta", "mid", "only-deployed", "only-local", "zeta"}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("differences listed as %q, want %q", listed, want)
+	}
+	snippets := strings.Split(first, "stringData:\n")[1:]
+	if len(snippets) == 0 {
+		t.Fatalf("report has no snippets:\n%s", first)
+	}
+	for _, snippet := range snippets {
+		var keys []string
+		for _, line := range strings.Split(snippet[:strings.Index(snippet, "```")], "\n") {
+			if line != "" {
+				keys = append(keys, strings.TrimSpace(line[:strings.Index(line, ":")]))
+			}
+		}
+		if !sort.StringsAreSorted(keys) {
+			t.Errorf("snippet keys %q are not sorted", keys)
+		}
+	}
+}
+
+// TestTextReportDataMatchFieldsDiffer checks that a resource whose data matches
+// but whose other fields differ is not reported as matching
+func TestTextReportDataMatchFieldsDiffer(t *testing.T) {
+	localChecksum, deployedChecksum := "a", "b"
+	tests := []struct {
+		name      string
+		result    ComparisonResult
+		wantMatch bool
+		want      string
+	}{
+		{
+			name:      "everything matches",
+			result:    ComparisonResult{MatchingKeys: 2},
+			wantMatch: true,
+		},
+		{
+			name:   "type differs",
+			result: ComparisonResult{MatchingKeys: 2, FieldDifferences: []FieldDifference{{Field: "type", Local: "Opaque", Deployed: "kubernetes.io/tls"}}},
+			want:   "Field differences found for Secret",
+		},
+		{
+			name:   "finalizer only deployed",
+			result: ComparisonResult{MatchingKeys: 2, FinalizersOnlyInDeployed: []string{"example.com/protect"}},
+			want:   "Finalizer differences found for Secret",
+		},
+		{
+			name:   "annotation differs",
+			result: ComparisonResult{MatchingKeys: 2, AnnotationDifferences: []SecretDifference{{Key: "checksum/config", Local: &localChecksum, Deployed: &deployedChecksum}}},
+			want:   "Metadata differences found for Secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.result.Kind, tt.result.Name, tt.result.Namespace = "Secret", "app", "default"
+			var out bytes.Buffer
+			reporter := &textReporter{w: &out, opts: TextOptions{SnippetIndent: 2}}
+			reporter.AddResult(tt.result)
+			report := out.String()
+			if got := strings.Contains(report, "All Secret match"); got != tt.wantMatch {
+				t.Errorf("all-match line printed: %v, want %v:\n%s", got, tt.wantMatch, report)
+			}
+			if !strings.Contains(report, tt.want) {
+				t.Errorf("report does not contain %q:\n%s", tt.want, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RunSummary is what a reporter needs to finish its report after the last result
+type RunSummary struct {
+	Stats             RunStats
+	DifferencesFound  bool
+	UnmatchedDeployed []unmatchedResource // deployed resources matching -selector without a local file
+	Orphans           []string            // deployed resources without a local manifest, with -detect-orphans
+	Interrupted       int                 // resources left unchecked because the run was interrupted
+	Files             int
+	Duration          time.Duration
+}
+
+// Reporter renders the comparison results in one output format. AddResult is
+// called once per resource in file order, Finish once at the end of the run.
+type Reporter interface {
+	AddResult(result ComparisonResult)
+	Finish(summary RunSummary) error
+}
+
+// jsonReporter writes the JSON report once the run is complete
+type jsonReporter struct {
+	w      io.Writer
+	report JSONReport
+}
+
+func (r *jsonReporter) AddResult(result ComparisonResult) { r.report.AddResult(result) }
+
+func (r *jsonReporter) Finish(summary RunSummary) error {
+	r.report.Orphans = summary.Orphans
+	r.report.UnmatchedDeployed = summary.UnmatchedDeployed
+	r.report.DriftedNamespaces = summary.Stats.DriftedNamespaces
+	r.report.DifferencesFound = summary.DifferencesFound
+	if err := r.report.Write(r.w); err != nil {
+		return fmt.Errorf("error writing JSON report: %w", err)
+	}
+	return nil
+}
+
+// jsonSummaryReporter writes only the aggregate counts as a single compact JSON line
+type jsonSummaryReporter struct {
+	w      io.Writer
+	report JSONReport
+}
+
+func (r *jsonSummaryReporter) AddResult(result ComparisonResult) { r.report.AddResult(result) }
+
+func (r *jsonSummaryReporter) Finish(summary RunSummary) error {
+	if err := json.NewEncoder(r.w).Encode(r.counts(summary)); err != nil {
+		return fmt.Errorf("error writing JSON summary: %w", err)
+	}
+	return nil
+}
+
+// counts returns the aggregate counts of the results added so far
+func (r *jsonSummaryReporter) counts(summary RunSummary) JSONRunSummary {
+	r.report.Orphans = summary.Orphans
+	r.report.UnmatchedDeployed = summary.UnmatchedDeployed
+	r.report.DifferencesFound = summary.DifferencesFound
+	r.report.summarize()
+	differingKeys := 0
+	for _, res := range r.report.Resources {
+		if !res.Managed || !r.report.SkipManaged {
+			differingKeys += len(res.Differences)
+		}
+	}
+	return JSONRunSummary{
+		Checked:       summary.Stats.Checked,
+		Drifted:       r.report.Summary.ResourcesWithDifferences,
+		Missing:       summary.Stats.NotFound,
+		Errors:        summary.Stats.Errors,
+		Orphans:       r.report.Summary.Orphans,
+		DifferingKeys: differingKeys,
+		Match:         !summary.DifferencesFound,
+	}
+}
+
+// logSummaryReporter logs the aggregate counts as a single JSON log line
+// (-log-format json), regardless of -log-level
+type logSummaryReporter struct {
+	summary jsonSummaryReporter
+}
+
+func (r *logSummaryReporter) AddResult(result ComparisonResult) { r.summary.AddResult(result) }
+
+func (r *logSummaryReporter) Finish(summary RunSummary) error {
+	counts := r.summary.counts(summary)
+	writeJSONLog(jsonLogLine{Level: "info", Message: "Summary", Summary: &counts})
+	return nil
+}
+
+// junitReporter writes the JUnit XML report once the run is complete
+type junitReporter struct {
+	w     io.Writer
+	suite *JUnitTestSuite
+}
+
+func (r *junitReporter) AddResult(result ComparisonResult) { r.suite.AddResult(result) }
+
+func (r *junitReporter) Finish(summary RunSummary) error {
+	for _, unmatched := range summary.UnmatchedDeployed {
+		r.suite.AddUnmatched(unmatched)
+	}
+	if err := r.suite.Write(r.w); err != nil {
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+	return nil
+}
+
+// markdownReporter writes the Markdown report once the run is complete
+type markdownReporter struct {
+	w      io.Writer
+	report MarkdownReport
+}
+
+func (r *markdownReporter) AddResult(result ComparisonResult) { r.report.AddResult(result) }
+
+func (r *markdownReporter) Finish(summary RunSummary) error {
+	r.report.UnmatchedDeployed = summary.UnmatchedDeployed
+	if err := r.report.Write(r.w); err != nil {
+		return fmt.Errorf("error writing Markdown report: %w", err)
+	}
+	return nil
+}
+
+// eventReporter emits a diff_found event per difference and a final run_complete event
+type eventReporter struct {
+	events *EventWriter
+}
+
+func (r *eventReporter) AddResult(result ComparisonResult) { emitResultEvents(r.events, result) }
+
+func (r *eventReporter) Finish(summary RunSummary) error {
+	r.events.Emit("run_complete", map[string]interface{}{
+		"files":             summary.Files,
+		"differences_found": summary.DifferencesFound,
+		"duration_ms":       summary.Duration.Milliseconds(),
+	})
+	return nil
+}
+
+// outputFormats are the values accepted by -output
+var outputFormats = []string{"text", "diff", "json", "json-summary", "junit", "markdown", "ndjson-events"}
+
+// formatPlaceholder in a single -output-file is replaced by each format's name
+const formatPlaceholder = "{format}"
+
+// outputTargets pairs every requested format with the file it is written to,
+// where "" or "-" stands for stdout. files is the -output-file list: empty for
+// stdout only, one name containing {format}, or one entry per format.
+func outputTargets(formats, files []string) ([]string, error) {
+	for _, format := range formats {
+		if !contains(outputFormats, format) {
+			return nil, fmt.Errorf("unsupported output format '%s'", format)
+		}
+	}
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no output format given")
+	}
+
+	targets := make([]string, len(formats))
+	switch {
+	case len(files) == 0:
+	case len(files) == 1 && strings.Contains(files[0], formatPlaceholder):
+		for i, format := range formats {
+			targets[i] = strings.ReplaceAll(files[0], formatPlaceholder, format)
+		}
+	case len(files) == len(formats):
+		copy(targets, files)
+	default:
+		return nil, fmt.Errorf("-output-file needs one file per -output format (%d given for %d formats), or a single name containing %s", len(files), len(formats), formatPlaceholder)
+	}
+
+	stdout := 0
+	for i, target := range targets {
+		if target == "-" {
+			targets[i] = ""
+		}
+		if targets[i] == "" {
+			stdout++
+		}
+	}
+	if stdout > 1 {
+		return nil, fmt.Errorf("only one output format can be written to stdout; use -output-file for the others")
+	}
+	return targets, nil
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// selectedResources holds the deployed Secrets and ConfigMaps matching a label
+// selector, keyed by kind, namespace and name
+type selectedResources map[string]*DeployedData
+
+// listSelected lists the Secrets and ConfigMaps in namespace matching selector
+func listSelected(ctx context.Context, clientset kubernetes.Interface, namespace, selector string) (selectedResources, error) {
+	selected := make(selectedResources)
+	secrets, err := listDeployedSecrets(ctx, clientset, namespace, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		selected[resourceKey("Secret", secret.Namespace, secret.Name)] = secret
+	}
+	configs, err := listDeployedConfigs(ctx, clientset, namespace, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		selected[resourceKey("ConfigMap", config.Namespace, config.Name)] = config
+	}
+	return selected, nil
+}
+
+// getter returns a resourceGetter answering from the listed resources,
+// so no further API calls are made
+func (s selectedResources) getter() resourceGetter {
+	return func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		return s[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())], nil
+	}
+}
+
+// unmatchedResource is a deployed resource matching -selector without a local file
+type unmatchedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (u unmatchedResource) String() string {
+	return fmt.Sprintf("%s '%s' in namespace '%s'", u.Kind, u.Name, u.Namespace)
+}
+
+// match returns the local resources with a selected deployed counterpart, and
+// the selected deployed resources without a local file
+func (s selectedResources) match(resources []LocalResource) (matched []LocalResource, unmatched []unmatchedResource) {
+	local := make(map[string]bool)
+	for _, resource := range resources {
+		key := resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())
+		if _, ok := s[key]; ok {
+			matched = append(matched, resource)
+			local[key] = true
+		} else {
+			debugf("Skipping %s '%s' in namespace '%s': not matched by -selector", resource.GetKind(), resource.GetName(), resource.GetNamespace())
+		}
+	}
+	for key, deployed := range s {
+		if !local[key] {
+			kind := "Secret"
+			if deployed.Type == "configmap" {
+				kind = "ConfigMap"
+			}
+			unmatched = append(unmatched, unmatchedResource{Kind: kind, Namespace: deployed.Namespace, Name: deployed.Name})
+		}
+	}
+	sort.Slice(unmatched, func(i, j int) bool { return unmatched[i].String() < unmatched[j].String() })
+	return matched, unmatched
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// sopsCommand is the sops binary used to decrypt files
+const sopsCommand = "sops"
+
+// decryptSops decrypts a SOPS-encrypted file by running `sops -d`.
+// The plaintext is only ever held in memory and never written to disk.
+func decryptSops(filePath string) ([]byte, error) {
+	cmd := exec.Command(sopsCommand, "--decrypt", filePath)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("error decrypting with sops: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("error decrypting with sops: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
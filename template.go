@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateSuffix marks local files rendered with text/template before parsing
+const templateSuffix = ".tmpl"
+
+// loadTemplateValues reads the YAML values file used to render templates
+func loadTemplateValues(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template values: %w", err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("error decoding template values '%s': %w", path, err)
+	}
+	return values, nil
+}
+
+// renderTemplate renders a manifest template with values. A missing value is
+// an error naming the template, line and key rather than rendering "<no value>".
+func renderTemplate(filePath string, data []byte, values map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(filePath)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// isTemplate reports whether a local file is a template
+func isTemplate(filePath string) bool {
+	return strings.HasSuffix(filePath, templateSuffix)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Client-side rate limits, matching the client-go defaults
+const (
+	defaultQPS   = 5
+	defaultBurst = 10
+)
+
+// throttleNoticeDelay is how long a request must wait for the rate limiter
+// before the throttling is reported
+const throttleNoticeDelay = 100 * time.Millisecond
+
+// throttledLimiter wraps the client-side rate limiter and logs when requests
+// have to wait for it
+type throttledLimiter struct {
+	flowcontrol.RateLimiter
+	burst int
+	once  sync.Once
+}
+
+func (l *throttledLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	l.report(logCtxFrom(ctx), time.Since(start))
+	return err
+}
+
+func (l *throttledLimiter) Accept() {
+	start := time.Now()
+	l.RateLimiter.Accept()
+	l.report(currentLogCtx(), time.Since(start))
+}
+
+// report logs a wait caused by the rate limiter with c; the first one at info level
+func (l *throttledLimiter) report(c logCtx, waited time.Duration) {
+	if waited < throttleNoticeDelay {
+		return
+	}
+	l.once.Do(func() {
+		c.infof("API requests are throttled client-side (-qps %g, -burst %d); raise them for faster scans", l.QPS(), l.burst)
+	})
+	c.debugf("Waited %s for the client-side rate limiter", waited.Round(time.Millisecond))
+}
+
+// throttleLogger logs requests the API server rejects with 429 Too Many Requests.
+// client-go retries them itself when the response carries a Retry-After header.
+type throttleLogger struct {
+	next http.RoundTripper
+}
+
+func (t *throttleLogger) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		c := logCtxFrom(req.Context())
+		if wait := resp.Header.Get("Retry-After"); wait != "" {
+			c.infof("API server throttled %s %s (429 Too Many Requests), retrying after %ss", req.Method, req.URL.Path, wait)
+		} else {
+			c.warnf("API server throttled %s %s (429 Too Many Requests)", req.Method, req.URL.Path)
+		}
+	}
+	return resp, err
+}
+
+// applyRateLimits sets the client-side rate limits on config and installs the
+// throttling loggers
+func applyRateLimits(config *rest.Config, qps float32, burst int) {
+	if qps <= 0 {
+		qps = defaultQPS
+	}
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+	config.QPS = qps
+	config.Burst = burst
+	config.RateLimiter = &throttledLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst), burst: burst}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper { return &throttleLogger{next: rt} })
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tlsCertificateKey is the key holding the PEM certificate chain of a kubernetes.io/tls Secret
+const tlsCertificateKey = "tls.crt"
+
+// compareTLSCertificates re-examines a differing tls.crt value by parsing both sides
+// as x509 certificates. A difference that is only PEM formatting is dropped; otherwise
+// the difference is annotated with what changed in the certificate. Values that do not
+// parse keep the raw comparison.
+func compareTLSCertificates(differences []SecretDifference) []SecretDifference {
+	for i, diff := range differences {
+		if diff.Key != tlsCertificateKey || diff.Local == nil || diff.Deployed == nil {
+			continue
+		}
+		local, err := parseCertificates(*diff.Local)
+		if err != nil {
+			debugf("Comparing local key '%s' as raw text: %v", diff.Key, err)
+			return differences
+		}
+		deployed, err := parseCertificates(*diff.Deployed)
+		if err != nil {
+			debugf("Comparing deployed key '%s' as raw text: %v", diff.Key, err)
+			return differences
+		}
+		notes := describeCertificateChanges(local, deployed)
+		if len(notes) == 0 {
+			infof("Certificates in key '%s' are identical; only the PEM formatting differs", diff.Key)
+			return append(differences[:i:i], differences[i+1:]...)
+		}
+		differences[i].Notes = notes
+		return differences
+	}
+	return differences
+}
+
+// parseCertificates decodes every CERTIFICATE block of a PEM chain
+func parseCertificates(value string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(value)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+// describeCertificateChanges lists the human-readable differences between two
+// certificate chains. It returns nil when both chains hold the same certificates.
+func describeCertificateChanges(local, deployed []*x509.Certificate) []string {
+	var notes []string
+	leafLocal, leafDeployed := local[0], deployed[0]
+	if !bytes.Equal(leafLocal.Raw, leafDeployed.Raw) {
+		if leafLocal.SerialNumber.Cmp(leafDeployed.SerialNumber) != 0 {
+			notes = append(notes, fmt.Sprintf("certificate serial changed: local %s, deployed %s", leafLocal.SerialNumber, leafDeployed.SerialNumber))
+		}
+		if leafLocal.Subject.String() != leafDeployed.Subject.String() {
+			notes = append(notes, fmt.Sprintf("subject differs: local %q, deployed %q", leafLocal.Subject, leafDeployed.Subject))
+		}
+		if leafLocal.Issuer.String() != leafDeployed.Issuer.String() {
+			notes = append(notes, fmt.Sprintf("issuer differs: local %q, deployed %q", leafLocal.Issuer, leafDeployed.Issuer))
+		}
+		if !leafLocal.NotAfter.Equal(leafDeployed.NotAfter) {
+			notes = append(notes, fmt.Sprintf("expiry differs: local %s, deployed %s", leafLocal.NotAfter.UTC().Format(time.RFC3339), leafDeployed.NotAfter.UTC().Format(time.RFC3339)))
+		}
+		if localNames, deployedNames := strings.Join(leafLocal.DNSNames, ","), strings.Join(leafDeployed.DNSNames, ","); localNames != deployedNames {
+			notes = append(notes, fmt.Sprintf("DNS names differ: local [%s], deployed [%s]", localNames, deployedNames))
+		}
+		notes = append(notes, fmt.Sprintf("fingerprint changed: local %s, deployed %s", certificateFingerprint(leafLocal), certificateFingerprint(leafDeployed)))
+	}
+	if !sameChain(local[1:], deployed[1:]) {
+		notes = append(notes, fmt.Sprintf("intermediate chain differs: local has %d certificates, deployed has %d", len(local)-1, len(deployed)-1))
+	}
+	return notes
+}
+
+// sameChain reports whether two certificate lists are identical
+func sameChain(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Raw, b[i].Raw) {
+			return false
+		}
+	}
+	return true
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of a certificate, shortened for display
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return fmt.Sprintf("sha256:%x", sum[:8])
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// valueTransformer pipes values through an external command before comparison
+type valueTransformer struct {
+	args []string
+}
+
+// newValueTransformer parses a -transform-cmd value. The command is split on
+// whitespace and run directly, not through a shell.
+func newValueTransformer(command string) (*valueTransformer, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty transform command")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("transform command '%s' not found: %w", args[0], err)
+	}
+	return &valueTransformer{args: args}, nil
+}
+
+// transform runs the command with value on stdin and returns its stdout. The key
+// and side ("local" or "deployed") are passed in the environment so one command
+// can handle several encodings.
+func (t *valueTransformer) transform(key, side, value string) (string, error) {
+	cmd := exec.Command(t.args[0], t.args[1:]...)
+	cmd.Env = append(os.Environ(), "SECRET_COMPARE_KEY="+key, "SECRET_COMPARE_SIDE="+side)
+	cmd.Stdin = strings.NewReader(value)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("error running transform command: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("error running transform command: %w", err)
+	}
+	return stdout.String(), nil
+}
+
+// transformData returns copies of the local and deployed data with every value
+// transformed. A key whose transformation fails on either side is reported as an
+// error and left out of both copies, so it can neither match nor differ.
+func (t *valueTransformer) transformData(local, deployed map[string]string, resource LocalResource) (map[string]string, map[string]string) {
+	failed := make(map[string]bool)
+	apply := func(data map[string]string, side string) map[string]string {
+		transformed := make(map[string]string, len(data))
+		for key, value := range data {
+			out, err := t.transform(key, side, value)
+			if err != nil {
+				errorf("Failed to transform %s key '%s' of %s '%s' in namespace '%s': %v", side, key, resource.GetKind(), resource.GetName(), resource.GetNamespace(), err)
+				failed[key] = true
+				continue
+			}
+			transformed[key] = out
+		}
+		return transformed
+	}
+	local, deployed = apply(local, "local"), apply(deployed, "deployed")
+	for key := range failed {
+		delete(local, key)
+		delete(deployed, key)
+	}
+	return local, deployed
+}
+
+// withRawValues returns differences found between transformed values with the
+// values before transformation, local and deployed, so reports, snippets and
+// patches never show the output of the transform command (e.g. decrypted text).
+// The raw values are displayed as opts displays any value, so
+// -ignore-trailing-newline and -show-normalized still apply to them.
+func withRawValues(differences []SecretDifference, local, deployed map[string]string, opts CompareOptions) []SecretDifference {
+	for i := range differences {
+		key := differences[i].Key
+		if differences[i].Local != nil {
+			value := opts.Display(local[key])
+			differences[i].Local = &value
+		}
+		if differences[i].Deployed != nil {
+			value := opts.Display(deployed[key])
+			differences[i].Deployed = &value
+		}
+	}
+	return differences
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-secret-compare/pkg/compare"
+)
+
+// TestWithRawValues checks that differences found between transformed values
+// report the values before transformation
+func TestWithRawValues(t *testing.T) {
+	rawLocal := map[string]string{"changed": "ENC(local)", "only-local": "ENC(new)"}
+	rawDeployed := map[string]string{"changed": "ENC(deployed)", "only-deployed": "ENC(old)"}
+	decrypt := func(data map[string]string) map[string]string {
+		out := make(map[string]string, len(data))
+		for key, value := range data {
+			out[key] = "plaintext " + value
+		}
+		return out
+	}
+	differences := withRawValues(compare.Data(decrypt(rawLocal), decrypt(rawDeployed), CompareOptions{}), rawLocal, rawDeployed, CompareOptions{})
+	value := func(v *string) string {
+		if v == nil {
+			return "<nil>"
+		}
+		return *v
+	}
+	var got []string
+	for _, diff := range differences {
+		got = append(got, diff.Key+" "+value(diff.Local)+" "+value(diff.Deployed))
+	}
+	want := []string{"changed ENC(local) ENC(deployed)", "only-deployed <nil> ENC(old)", "only-local ENC(new) <nil>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("differences = %q, want %q", got, want)
+	}
+	if patch := renderPatch(ComparisonResult{Kind: "Secret", Name: "app", Namespace: "default", MergeField: "stringData", Differences: differences}, 2); strings.Contains(patch, "plaintext") {
+		t.Errorf("patch contains transformed values:\n%s", patch)
+	}
+}
+
+// TestWithRawValuesNormalized checks that raw values still get the normalized
+// display of -ignore-trailing-newline and -show-normalized with -transform-cmd
+func TestWithRawValuesNormalized(t *testing.T) {
+	rawLocal := map[string]string{"cert": "ENC(local)  \r\nline\n"}
+	rawDeployed := map[string]string{"cert": "ENC(deployed)\nline"}
+	decrypt := func(data map[string]string) map[string]string {
+		return map[string]string{"cert": "plaintext " + data["cert"]}
+	}
+	opts := CompareOptions{IgnoreTrailingNewline: true, NormalizeWhitespace: true, ShowNormalized: true}
+	differences := withRawValues(compare.Data(decrypt(rawLocal), decrypt(rawDeployed), opts), rawLocal, rawDeployed, opts)
+	if len(differences) != 1 {
+		t.Fatalf("got %d differences, want 1", len(differences))
+	}
+	if got, want := *differences[0].Local, "ENC(local)\nline"; got != want {
+		t.Errorf("local = %q, want %q", got, want)
+	}
+	if got, want := *differences[0].Deployed, "ENC(deployed)\nline"; got != want {
+		t.Errorf("deployed = %q, want %q", got, want)
+	}
+
+	opts.ShowNormalized = false
+	differences = withRawValues(compare.Data(decrypt(rawLocal), decrypt(rawDeployed), opts), rawLocal, rawDeployed, opts)
+	if got, want := *differences[0].Local, "ENC(local)  \r\nline"; got != want {
+		t.Errorf("local without -show-normalized = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+const (
+	// watchInterval is how often watched files are checked for changes
+	watchInterval = 500 * time.Millisecond
+	// watchDebounce is how long files must stay unchanged before a re-run,
+	// so editors writing a file in several steps trigger a single run
+	watchDebounce = 300 * time.Millisecond
+)
+
+// fileState is the modification time and size of a watched file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshotFiles records the state of every file returned by list
+func snapshotFiles(list func() ([]string, error)) (map[string]fileState, error) {
+	files, err := list()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]fileState, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			// Deleted between listing and stat; it shows up as a change
+			continue
+		}
+		snapshot[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return snapshot, nil
+}
+
+// sameSnapshot reports whether two snapshots hold the same files in the same state
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		if other, ok := b[file]; !ok || other != state {
+			return false
+		}
+	}
+	return true
+}
+
+// watchAndRerun runs the comparison in a child process with args, then polls the
+// files returned by list and runs it again after they change, clearing the screen
+// first. It returns the exit code of the last run once interrupted with Ctrl-C.
+func watchAndRerun(list func() ([]string, error), args []string) int {
+	executable, err := os.Executable()
+	if err != nil {
+		fatalf("Failed to locate the executable for -watch: %v", err)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	run := func() int {
+		// Clear the screen and move the cursor home
+		fmt.Print("\033[H\033[2J")
+		cmd := exec.Command(executable, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return 0
+		case errors.As(err, &exitErr):
+			return exitErr.ExitCode()
+		default:
+			errorf("Failed to run comparison: %v", err)
+			return 1
+		}
+	}
+
+	snapshot, err := snapshotFiles(list)
+	if err != nil {
+		fatalf("Failed to list watched files: %v", err)
+	}
+	exitCode := run()
+	infof("Watching %d files for changes (Ctrl-C to stop)", len(snapshot))
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return exitCode
+		case <-ticker.C:
+		}
+
+		current, err := snapshotFiles(list)
+		if err != nil {
+			errorf("Failed to list watched files: %v", err)
+			continue
+		}
+		if sameSnapshot(snapshot, current) {
+			continue
+		}
+
+		// Debounce: wait until the files stop changing
+		for {
+			select {
+			case <-interrupt:
+				return exitCode
+			case <-time.After(watchDebounce):
+			}
+			settled, err := snapshotFiles(list)
+			if err != nil || sameSnapshot(current, settled) {
+				break
+			}
+			current = settled
+		}
+
+		snapshot = current
+		exitCode = run()
+		infof("Watching %d files for changes (Ctrl-C to stop)", len(snapshot))
+	}
+}
+
+// withoutWatchFlag returns args with every form of the -watch flag removed
+func withoutWatchFlag(args []string) []string {
+	var filtered []string
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (name == "watch" || strings.HasPrefix(name, "watch=")) {
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
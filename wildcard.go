@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// anyNamespace as a namespace looks a resource up by name in every namespace
+const anyNamespace = "*"
+
+// findInAllNamespaces lists the deployed resources of the same kind and name as
+// resource across all namespaces, sorted by namespace
+func findInAllNamespaces(ctx context.Context, clientset kubernetes.Interface, resource LocalResource) ([]*DeployedData, error) {
+	opts := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", resource.GetName()).String()}
+	var found []*DeployedData
+	var err error
+	switch resource.GetKind() {
+	case "Secret":
+		found, err = listDeployedSecrets(ctx, clientset, metav1.NamespaceAll, opts)
+	case "ConfigMap":
+		found, err = listDeployedConfigs(ctx, clientset, metav1.NamespaceAll, opts)
+	default:
+		return nil, fmt.Errorf("unsupported resource type: %s", resource.GetKind())
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Namespace < found[j].Namespace })
+	return found, nil
+}
+
+// wildcardGetter answers lookups of resources found by findInAllNamespaces from
+// found, and those still in anyNamespace (found nowhere) as missing. Other
+// resources are looked up with next.
+func wildcardGetter(found selectedResources, next resourceGetter) resourceGetter {
+	return func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		if resource.GetNamespace() == anyNamespace {
+			return nil, nil
+		}
+		if deployed, ok := found[resourceKey(resource.GetKind(), resource.GetNamespace(), resource.GetName())]; ok {
+			return deployed, nil
+		}
+		return next(ctx, resource)
+	}
+}
+
+// withoutUnresolved returns the resources whose namespace is known, leaving out
+// those still in anyNamespace because they were found nowhere, so no namespaced
+// API calls are made for namespace '*'
+func withoutUnresolved(resources []LocalResource) []LocalResource {
+	resolved := make([]LocalResource, 0, len(resources))
+	for _, resource := range resources {
+		if resource.GetNamespace() != anyNamespace {
+			resolved = append(resolved, resource)
+		}
+	}
+	return resolved
+}
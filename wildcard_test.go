@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestUnresolvedWildcardMakesNoCalls checks that a resource left in namespace
+// '*' because it was found nowhere causes no namespaced API calls
+func TestUnresolvedWildcardMakesNoCalls(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "prod"}},
+	)
+	resources := []LocalResource{testSecret("prod", "db"), testSecret(anyNamespace, "missing")}
+
+	clusterResources := withoutUnresolved(resources)
+	if len(clusterResources) != 1 || clusterResources[0].GetName() != "db" {
+		t.Fatalf("withoutUnresolved() kept %d resources, want only 'db'", len(clusterResources))
+	}
+	orphans, err := findOrphans(context.Background(), clientset, clusterResources, ResourceFilter{}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0] != "Secret 'orphan' in namespace 'prod'" {
+		t.Errorf("orphans = %q, want only 'orphan'", orphans)
+	}
+
+	get := wildcardGetter(selectedResources{}, clusterGetter(clientset, time.Second, 1))
+	deployed, err := get(context.Background(), resources[1])
+	if deployed != nil || err != nil {
+		t.Errorf("lookup in namespace '*' = %+v, %v; want not found", deployed, err)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetNamespace() == anyNamespace {
+			t.Errorf("made a %s %s call in namespace '%s'", action.GetVerb(), action.GetResource().Resource, anyNamespace)
+		}
+	}
+}
+
+func TestWildcardGetter(t *testing.T) {
+	found := selectedResources{
+		resourceKey("Secret", "prod", "db"): &DeployedData{Name: "db", Namespace: "prod"},
+	}
+	var nextCalls []string
+	next := func(ctx context.Context, resource LocalResource) (*DeployedData, error) {
+		nextCalls = append(nextCalls, resource.GetNamespace()+"/"+resource.GetName())
+		return &DeployedData{Name: resource.GetName(), Namespace: resource.GetNamespace()}, nil
+	}
+	get := wildcardGetter(found, next)
+
+	if deployed, _ := get(context.Background(), testSecret("prod", "db")); deployed != found[resourceKey("Secret", "prod", "db")] {
+		t.Errorf("resolved resource was not answered from found: %+v", deployed)
+	}
+	if deployed, _ := get(context.Background(), testSecret(anyNamespace, "missing")); deployed != nil {
+		t.Errorf("unresolved resource = %+v, want not found", deployed)
+	}
+	if deployed, _ := get(context.Background(), testSecret("staging", "api")); deployed == nil || deployed.Namespace != "staging" {
+		t.Errorf("other resource = %+v, want it from next", deployed)
+	}
+	if len(nextCalls) != 1 || nextCalls[0] != "staging/api" {
+		t.Errorf("next called for %q, want only staging/api", nextCalls)
+	}
+}